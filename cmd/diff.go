@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+// errTagsDiffer is returned by the diff command with --exit-code when the
+// compared tag counts differ.
+var errTagsDiffer = &statusError{err: errors.New("tag counts differ"), code: exitFailure}
+
+type diffOptions struct {
+	vault    string
+	format   outputFormat
+	exitCode bool
+	scan     scanOptions
+}
+
+func newDiffCmd(version string) *cobra.Command {
+	var opts diffOptions
+
+	cmd := &cobra.Command{
+		Use:   "diff <from> [to]",
+		Short: "Compare tag counts between two snapshots of a vault",
+		Long: `Compare tag counts between two snapshots of a vault.
+
+A snapshot is either a git revision of the vault, or the path to a cache file
+(.tobi.json) kept from an earlier scan. If to is omitted, from is compared
+against the working tree of the vault.
+
+With --exit-code, the exit status is 1 if tag counts differ and 0 if they do
+not. Errors, such as an unknown revision or an unreadable vault, always exit
+with status 2.`,
+		Args: cobra.RangeArgs(1, 2),
+		Example: `
+		# what changed since the last commit
+		tobi diff HEAD --vault /path/to/your/vault
+
+		# what changed between two tags
+		tobi diff v1.0 v2.0
+
+		# compare against a cache file saved before a cleanup
+		tobi diff before.json
+
+		# fail in CI if tag counts changed
+		tobi diff main --exit-code
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var vaultArgs []string
+			if opts.vault != "" {
+				vaultArgs = append(vaultArgs, opts.vault)
+			}
+			root, err := vaultFromArgs(vaultArgs)
+			if err != nil {
+				return err
+			}
+
+			from, err := snapshot(cmd.Context(), root, args[0], version, opts.scan)
+			if err != nil {
+				return err
+			}
+
+			// an empty spec means the working tree
+			var toSpec string
+			if len(args) == 2 {
+				toSpec = args[1]
+			}
+			to, err := snapshot(cmd.Context(), root, toSpec, version, opts.scan)
+			if err != nil {
+				return err
+			}
+
+			d := diffCounts(from, to)
+			if err := d.render(cmd.OutOrStdout(), opts.format); err != nil {
+				return err
+			}
+
+			if opts.exitCode && !d.empty() {
+				// not a usage error, the diff itself is the output
+				cmd.SilenceUsage = true
+				return errTagsDiffer
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&opts.vault, "vault", "V", "", "path to the vault. Defaults to OBSIDIAN_VAULT_PATH.")
+	addFormatFlag(cmd, &opts.format)
+	flags.BoolVar(&opts.exitCode, "exit-code", false, "exit with status 1 if tag counts differ")
+	addScanFlags(cmd, &opts.scan)
+
+	reportsStatus(cmd)
+
+	return cmd
+}
+
+// snapshot returns the tag counts of the vault at root as described by spec:
+// the working tree if spec is empty, the cache file at spec if it is an
+// existing file, or else the git revision spec.
+//
+// Tags excluded by the exclude file of the vault are dropped. Revisions are
+// filtered with the exclude file as of that revision, and cache files with the
+// exclude file of the working tree.
+func snapshot(ctx context.Context, root, spec, version string, opts scanOptions) (*vault.Result, error) {
+	if spec != "" {
+		if info, err := os.Stat(spec); err == nil && info.Mode().IsRegular() {
+			excludes, err := tagx.NewTagGlobs(filepath.Join(root, vault.ExcludeFile))
+			if err != nil {
+				return nil, err
+			}
+			return vault.ReadCacheFile(spec, vault.WithExcludes(excludes))
+		}
+	}
+
+	opts.rev = spec
+	sc, res, err := scanVault(ctx, root, version, opts, vault.WithExcludeFile())
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	return res, nil
+}
+
+// tagDelta is the change in the count of a single tag between two snapshots.
+type tagDelta struct {
+	Tag   string `json:"tag" yaml:"tag"`
+	From  int    `json:"from" yaml:"from"`
+	To    int    `json:"to" yaml:"to"`
+	Delta int    `json:"delta" yaml:"delta"`
+}
+
+// tagDiff is the difference between the tag counts of two snapshots. Tags
+// within each group are sorted by decreasing magnitude of change, then by
+// name.
+type tagDiff struct {
+	From    string     `json:"from" yaml:"from"`
+	To      string     `json:"to" yaml:"to"`
+	Added   []tagDelta `json:"added" yaml:"added"`
+	Removed []tagDelta `json:"removed" yaml:"removed"`
+	Changed []tagDelta `json:"changed" yaml:"changed"`
+}
+
+func diffCounts(from, to *vault.Result) tagDiff {
+	d := tagDiff{
+		From:    from.Root,
+		To:      to.Root,
+		Added:   []tagDelta{},
+		Removed: []tagDelta{},
+		Changed: []tagDelta{},
+	}
+
+	tags := slices.Sorted(maps.Keys(from.Counts))
+	for t := range maps.Keys(to.Counts) {
+		if _, ok := from.Counts[t]; !ok {
+			tags = append(tags, t)
+		}
+	}
+
+	for _, t := range tags {
+		td := tagDelta{Tag: t, From: from.Counts[t], To: to.Counts[t]}
+		td.Delta = td.To - td.From
+
+		switch {
+		case td.From == 0:
+			d.Added = append(d.Added, td)
+		case td.To == 0:
+			d.Removed = append(d.Removed, td)
+		case td.Delta != 0:
+			d.Changed = append(d.Changed, td)
+		}
+	}
+
+	for _, g := range d.groups() {
+		slices.SortFunc(g.deltas, func(a, b tagDelta) int {
+			return cmp.Or(
+				cmp.Compare(abs(b.Delta), abs(a.Delta)),
+				cmp.Compare(a.Tag, b.Tag),
+			)
+		})
+	}
+
+	return d
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// empty reports whether the two snapshots have the same tag counts.
+func (d tagDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// deltaGroup is a named group of changes of a tagDiff.
+type deltaGroup struct {
+	name   string
+	deltas []tagDelta
+}
+
+// groups returns the groups of changes in the order they are printed.
+func (d tagDiff) groups() []deltaGroup {
+	return []deltaGroup{
+		{"added", d.Added},
+		{"removed", d.Removed},
+		{"changed", d.Changed},
+	}
+}
+
+type deltaRecord struct {
+	Type string `json:"type"`
+	tagDelta
+}
+
+func (d tagDiff) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, td := range d.Added {
+			fmt.Fprintf(tw, "+ %s\t%d\n", td.Tag, td.To)
+		}
+		for _, td := range d.Removed {
+			fmt.Fprintf(tw, "- %s\t%d\n", td.Tag, td.From)
+		}
+		for _, td := range d.Changed {
+			fmt.Fprintf(tw, "~ %s\t%d -> %d (%+d)\n", td.Tag, td.From, td.To, td.Delta)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+		return err
+	case jsonFormat, yamlFormat:
+		return writeDocument(w, format, d)
+	case ndjsonFormat:
+		var records []any
+		for _, g := range d.groups() {
+			for _, td := range g.deltas {
+				records = append(records, deltaRecord{Type: g.name, tagDelta: td})
+			}
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		var rows [][]string
+		for _, g := range d.groups() {
+			for _, td := range g.deltas {
+				rows = append(rows, []string{
+					g.name,
+					td.Tag,
+					strconv.Itoa(td.From),
+					strconv.Itoa(td.To),
+					strconv.Itoa(td.Delta),
+				})
+			}
+		}
+		return writeTable(w, format, []string{"change", "tag", "from", "to", "delta"}, rows)
+	}
+
+	return nil
+}
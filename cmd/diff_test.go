@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_diffCounts(t *testing.T) {
+	testCases := []struct {
+		name string
+		from map[string]int
+		to   map[string]int
+		want tagDiff
+	}{
+		{
+			name: "no changes",
+			from: map[string]int{"golang": 2},
+			to:   map[string]int{"golang": 2},
+			want: tagDiff{Added: []tagDelta{}, Removed: []tagDelta{}, Changed: []tagDelta{}},
+		},
+		{
+			name: "added, removed and changed",
+			from: map[string]int{"golang": 2, "ml": 3, "rust": 1, "cli": 4},
+			to:   map[string]int{"golang": 5, "machine-learning": 3, "ai": 3, "cli": 3},
+			want: tagDiff{
+				Added: []tagDelta{
+					{Tag: "ai", To: 3, Delta: 3},
+					{Tag: "machine-learning", To: 3, Delta: 3},
+				},
+				Removed: []tagDelta{
+					{Tag: "ml", From: 3, Delta: -3},
+					{Tag: "rust", From: 1, Delta: -1},
+				},
+				Changed: []tagDelta{
+					{Tag: "golang", From: 2, To: 5, Delta: 3},
+					{Tag: "cli", From: 4, To: 3, Delta: -1},
+				},
+			},
+		},
+		{
+			name: "empty snapshots",
+			from: map[string]int{},
+			to:   map[string]int{"golang": 1},
+			want: tagDiff{
+				Added:   []tagDelta{{Tag: "golang", To: 1, Delta: 1}},
+				Removed: []tagDelta{},
+				Changed: []tagDelta{},
+			},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got := diffCounts(&vault.Result{Counts: tt.from}, &vault.Result{Counts: tt.to})
+			r.Equal(tt.want, got)
+		})
+	}
+}
+
+func Test_tagDiff_render(t *testing.T) {
+	d := tagDiff{
+		From:    "/vault@HEAD",
+		To:      "/vault",
+		Added:   []tagDelta{{Tag: "ai", To: 3, Delta: 3}},
+		Removed: []tagDelta{{Tag: "ml", From: 3, Delta: -3}},
+		Changed: []tagDelta{{Tag: "golang", From: 2, To: 5, Delta: 3}},
+	}
+
+	testCases := []struct {
+		name     string
+		format   outputFormat
+		expected string
+	}{
+		{
+			name:   "text",
+			format: textFormat,
+			expected: `+ ai      3
+- ml      3
+~ golang  2 -> 5 (+3)
+1 added, 1 removed, 1 changed
+`,
+		},
+		{
+			name:   "ndjson",
+			format: ndjsonFormat,
+			expected: `{"type":"added","tag":"ai","from":0,"to":3,"delta":3}
+{"type":"removed","tag":"ml","from":3,"to":0,"delta":-3}
+{"type":"changed","tag":"golang","from":2,"to":5,"delta":3}
+`,
+		},
+		{
+			name:   "csv",
+			format: csvFormat,
+			expected: `change,tag,from,to,delta
+added,ai,0,3,3
+removed,ml,3,0,-3
+changed,golang,2,5,3
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			r.NoError(d.render(&buf, tt.format))
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func Test_diffCmd(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"a.md": "#golang #ml",
+			"b.md": "#golang",
+		}),
+	)
+	defer dir.Remove()
+
+	repo, err := git.PlainInit(dir.Path(), false)
+	r.NoError(err)
+	wt, err := repo.Worktree()
+	r.NoError(err)
+	commit := func() {
+		r.NoError(wt.AddWithOptions(&git.AddOptions{All: true}))
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		_, err := wt.Commit("commit", &git.CommitOptions{Author: sig})
+		r.NoError(err)
+	}
+	commit()
+
+	// keep the cache of the first commit as a snapshot
+	run := func(args ...string) (string, error) {
+		var buf strings.Builder
+		c := NewRootCmd("test")
+		c.SetOut(&buf)
+		c.SetErr(&strings.Builder{})
+		c.SetArgs(append(args, "--vault", dir.Path()))
+		err := c.Execute()
+		return buf.String(), err
+	}
+	_, err = run("diff", "HEAD")
+	r.NoError(err)
+	snap := dir.Join("snapshot.json")
+	data, err := os.ReadFile(dir.Join(vault.CacheFile))
+	r.NoError(err)
+	r.NoError(os.WriteFile(snap, data, 0o644))
+
+	r.NoError(os.WriteFile(dir.Join("a.md"), []byte("#golang #machine-learning"), 0o644))
+	r.NoError(os.WriteFile(dir.Join("c.md"), []byte("#golang"), 0o644))
+	commit()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "working tree against revision",
+			args:     []string{"HEAD"},
+			expected: "0 added, 0 removed, 0 changed\n",
+		},
+		{
+			name:     "two revisions",
+			args:     []string{"HEAD~1", "HEAD"},
+			expected: "+ machine-learning  1\n- ml                1\n~ golang            2 -> 3 (+1)\n1 added, 1 removed, 1 changed\n",
+		},
+		{
+			name:     "cache file against working tree",
+			args:     []string{snap},
+			expected: "+ machine-learning  1\n- ml                1\n~ golang            2 -> 3 (+1)\n1 added, 1 removed, 1 changed\n",
+		},
+		{
+			name:     "revision against cache file",
+			args:     []string{"HEAD~1", snap},
+			expected: "0 added, 0 removed, 0 changed\n",
+		},
+		{
+			name:     "exit code without changes",
+			args:     []string{"HEAD", "--exit-code"},
+			expected: "0 added, 0 removed, 0 changed\n",
+		},
+		{
+			name:     "exit code with changes",
+			args:     []string{"HEAD~1", "--exit-code"},
+			expected: "+ machine-learning  1\n- ml                1\n~ golang            2 -> 3 (+1)\n1 added, 1 removed, 1 changed\n",
+			wantErr:  errTagsDiffer,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got, err := run(append([]string{"diff"}, tt.args...)...)
+			if tt.wantErr != nil {
+				r.ErrorIs(err, tt.wantErr)
+			} else {
+				r.NoError(err)
+			}
+			r.Equal(tt.expected, got)
+		})
+	}
+
+	_, err = run("diff", "no-such-rev")
+	r.Error(err)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd(version string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export tag relations of a vault",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newExportCooccurrenceCmd(version),
+		newExportGraphCmd(version),
+	)
+
+	return cmd
+}
+
+type cooccurrenceOptions struct {
+	limit    int
+	sort     pairSort
+	minNotes int
+	format   outputFormat
+	scan     scanOptions
+}
+
+func newExportCooccurrenceCmd(version string) *cobra.Command {
+	var opts cooccurrenceOptions
+
+	cmd := &cobra.Command{
+		Use:     "cooccurrence [path]",
+		Aliases: []string{"cooc"},
+		Short:   "Export every pair of tags used together, with scores",
+		Args:    cobra.RangeArgs(0, 1),
+		Example: `
+		# all pairs of tags as CSV
+		tobi export cooccurrence /path/to/your/vault --format csv
+
+		# pairs carried together by at least 3 notes, by number of notes
+		tobi export cooc --min-notes 3 --sort notes
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			pairs := vault.NewCooccurrence(res.Notes).Pairs(opts.minNotes)
+			sortPairs(pairs, opts.sort)
+			if opts.limit > 0 {
+				pairs = pairs[:min(len(pairs), opts.limit)]
+			}
+
+			report := cooccurrenceReport{
+				Vault: res.Root,
+				Notes: len(res.Notes),
+				Pairs: make([]tagPair, 0, len(pairs)),
+			}
+			for _, p := range pairs {
+				report.Pairs = append(report.Pairs, tagPair(p))
+			}
+
+			return report.render(cmd.OutOrStdout(), opts.format)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.IntVarP(&opts.limit, "limit", "l", 0, "number of pairs to export. Non-positive values mean all.")
+	addPairFlags(cmd, &opts.sort, &opts.minNotes)
+	addFormatFlag(cmd, &opts.format)
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// tagPair is a pair of tags carried together by at least one note.
+type tagPair struct {
+	A       string  `json:"a" yaml:"a"`
+	B       string  `json:"b" yaml:"b"`
+	Notes   int     `json:"notes" yaml:"notes"`
+	Jaccard float64 `json:"jaccard" yaml:"jaccard"`
+	PMI     float64 `json:"pmi" yaml:"pmi"`
+}
+
+// cooccurrenceReport is the document written by the json and yaml formats.
+type cooccurrenceReport struct {
+	Vault string    `json:"vault" yaml:"vault"`
+	Notes int       `json:"notes" yaml:"notes"`
+	Pairs []tagPair `json:"pairs" yaml:"pairs"`
+}
+
+type pairRecord struct {
+	Type string `json:"type"`
+	tagPair
+}
+
+func (r cooccurrenceReport) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, p := range r.Pairs {
+			fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%s\t%s\n", p.Notes, p.Jaccard, p.PMI, p.A, p.B)
+		}
+		return tw.Flush()
+	case jsonFormat, yamlFormat:
+		return writeDocument(w, format, r)
+	case ndjsonFormat:
+		records := make([]any, 0, len(r.Pairs))
+		for _, p := range r.Pairs {
+			records = append(records, pairRecord{Type: "pair", tagPair: p})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		rows := make([][]string, 0, len(r.Pairs))
+		for _, p := range r.Pairs {
+			rows = append(rows, []string{
+				p.A,
+				p.B,
+				strconv.Itoa(p.Notes),
+				strconv.FormatFloat(p.Jaccard, 'f', -1, 64),
+				strconv.FormatFloat(p.PMI, 'f', -1, 64),
+			})
+		}
+		return writeTable(w, format, []string{"a", "b", "notes", "jaccard", "pmi"}, rows)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_exportCooccurrenceCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFiles(cooccurrenceFiles))
+	defer dir.Remove()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "text",
+			args: []string{},
+			expected: `2  0.67  0.74   cobra  golang
+1  0.50  1.32   cli    rust
+1  0.33  0.32   cli    cobra
+1  0.25  -0.26  cli    golang
+`,
+		},
+		{
+			name: "csv by notes",
+			args: []string{"--sort", "notes", "--format", "csv", "--limit", "2"},
+			expected: `a,b,notes,jaccard,pmi
+cobra,golang,2,0.6666666666666666,0.7369655941662062
+cli,cobra,1,0.3333333333333333,0.3219280948873623
+`,
+		},
+		{
+			name:     "min notes",
+			args:     []string{"--min-notes", "2", "--format", "ndjson"},
+			expected: `{"type":"pair","a":"cobra","b":"golang","notes":2,"jaccard":0.6666666666666666,"pmi":0.7369655941662062}` + "\n",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"export", "cooc", dir.Path()}, tt.args...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
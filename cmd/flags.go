@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"cmp"
 	"fmt"
 	"iter"
+	"maps"
+	"os"
+	"runtime"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/thediveo/enumflag/v2"
@@ -17,32 +22,54 @@ const (
 	name displayMode = iota
 	count
 	relative
+	tree
 )
 
 var displayModeIDs = map[displayMode][]string{
 	name:     {"name", "n"},
 	count:    {"count", "c"},
 	relative: {"relative", "r"},
+	tree:     {"tree", "t"},
 }
 
-// displayModeVariants returns an iterator that yields the canonical variant
-// string representation for each display mode
-func displayModeVariants() iter.Seq[string] {
+type outputFormat enumflag.Flag
+
+const (
+	textFormat outputFormat = iota
+	jsonFormat
+	ndjsonFormat
+	csvFormat
+	tsvFormat
+	yamlFormat
+)
+
+var outputFormatIDs = map[outputFormat][]string{
+	textFormat:   {"text"},
+	jsonFormat:   {"json"},
+	ndjsonFormat: {"ndjson"},
+	csvFormat:    {"csv"},
+	tsvFormat:    {"tsv"},
+	yamlFormat:   {"yaml", "yml"},
+}
+
+// enumVariants returns an iterator that yields the canonical variant
+// string representation for each value of an enum flag, in ascending order.
+func enumVariants[E cmp.Ordered](ids map[E][]string) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		for _, v := range displayModeIDs {
-			if !yield(v[0]) {
+		for _, k := range slices.Sorted(maps.Keys(ids)) {
+			if !yield(ids[k][0]) {
 				return
 			}
 		}
 	}
 }
 
-// displayModeAliases returns an iterator that yields all variant string
-// representations (canonical and aliases) for every display mode.
-func displayModeAliases() iter.Seq[string] {
+// enumAliases returns an iterator that yields all variant string
+// representations (canonical and aliases) for every value of an enum flag.
+func enumAliases[E cmp.Ordered](ids map[E][]string) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		for _, v := range displayModeIDs {
-			for _, a := range v {
+		for _, k := range slices.Sorted(maps.Keys(ids)) {
+			for _, a := range ids[k] {
 				if !yield(a) {
 					return
 				}
@@ -52,10 +79,66 @@ func displayModeAliases() iter.Seq[string] {
 }
 
 func displayModeUsage() string {
-	v := slices.Collect(displayModeVariants())
+	v := slices.Collect(enumVariants(displayModeIDs))
 	return fmt.Sprintf("display mode (%s)", strings.Join(v, "|"))
 }
 
 func completeDisplayModeFlag(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
-	return slices.Collect(displayModeAliases()), cobra.ShellCompDirectiveDefault
+	return slices.Collect(enumAliases(displayModeIDs)), cobra.ShellCompDirectiveDefault
+}
+
+func outputFormatUsage() string {
+	v := slices.Collect(enumVariants(outputFormatIDs))
+	return fmt.Sprintf("output format (%s)", strings.Join(v, "|"))
+}
+
+func completeOutputFormatFlag(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return slices.Collect(enumAliases(outputFormatIDs)), cobra.ShellCompDirectiveDefault
+}
+
+// addFormatFlag adds the --format flag and its completion to cmd.
+func addFormatFlag(cmd *cobra.Command, format *outputFormat) {
+	cmd.Flags().VarP(
+		enumflag.New(format, "format", outputFormatIDs, enumflag.EnumCaseSensitive),
+		"format", "f", outputFormatUsage(),
+	)
+
+	if err := cmd.RegisterFlagCompletionFunc("format", completeOutputFormatFlag); err != nil {
+		os.Exit(1)
+	}
+}
+
+// scanOptions controls how a vault is scanned.
+type scanOptions struct {
+	noCache bool
+	// jobs is the maximum number of notes read concurrently.
+	jobs int
+	// timeout is the maximum duration of the scan. Zero means no timeout.
+	timeout time.Duration
+	// rev is the git revision of the vault to scan. Empty means the working
+	// tree.
+	rev string
+}
+
+// addScanFlags adds the --jobs, --timeout and --no-cache flags to cmd.
+func addScanFlags(cmd *cobra.Command, opts *scanOptions) {
+	addJobsFlag(cmd, opts)
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 0, "maximum duration of the scan, e.g. 30s. Zero means no timeout.")
+	addNoCacheFlag(cmd, opts)
+}
+
+// addJobsFlag adds the --jobs flag to cmd, for commands that keep scanning
+// and have no use for --timeout.
+func addJobsFlag(cmd *cobra.Command, opts *scanOptions) {
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", runtime.GOMAXPROCS(0), "number of notes read concurrently. Non-positive values mean GOMAXPROCS.")
+}
+
+// addNoCacheFlag adds the --no-cache flag to cmd.
+func addNoCacheFlag(cmd *cobra.Command, opts *scanOptions) {
+	cmd.Flags().BoolVarP(&opts.noCache, "no-cache", "n", false, "disable cache")
+}
+
+// addRevFlag adds the --rev flag to cmd.
+func addRevFlag(cmd *cobra.Command, opts *scanOptions) {
+	cmd.Flags().StringVar(&opts.rev, "rev", "", "scan the vault as of a git revision, e.g. HEAD~3, instead of the working tree")
 }
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/goccy/go-yaml"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+)
+
+// scanInfo describes the scan that produced a set of tag counts.
+type scanInfo struct {
+	// Vault is the absolute path of the scanned vault.
+	Vault string
+	// Notes is the number of notes in the vault.
+	Notes int
+	// CacheHit is true if every note was served from the cache.
+	CacheHit bool
+}
+
+func newScanInfo(res *vault.Result) scanInfo {
+	return scanInfo{
+		Vault:    res.Root,
+		Notes:    len(res.Notes),
+		CacheHit: res.CacheHit,
+	}
+}
+
+// tagStat is a single tag in structured output.
+type tagStat struct {
+	Tag   string `json:"tag" yaml:"tag"`
+	Count int    `json:"count" yaml:"count"`
+	// Relative is the share of the tag in the total number of tags, in percent.
+	Relative float64 `json:"relative" yaml:"relative"`
+}
+
+// tagReport is the document written by the json and yaml formats.
+type tagReport struct {
+	Vault    string    `json:"vault" yaml:"vault"`
+	Notes    int       `json:"notes" yaml:"notes"`
+	Total    int       `json:"total" yaml:"total"`
+	CacheHit bool      `json:"cacheHit" yaml:"cacheHit"`
+	Tags     []tagStat `json:"tags" yaml:"tags"`
+}
+
+// render writes the tag counts to w in the output format selected in opts.
+// The text format honors the display mode, while structured formats always
+// include the count and relative frequency of every tag.
+func (tc tagCounts) render(w io.Writer, info scanInfo, opts rootOptions) error {
+	if opts.format == textFormat {
+		tc.fPrint(w, opts)
+		return nil
+	}
+
+	report := tc.report(info, opts.limit)
+
+	switch opts.format {
+	case jsonFormat, yamlFormat:
+		return writeDocument(w, opts.format, report)
+	case ndjsonFormat:
+		// the first record holds the scan metadata
+		records := []any{scanRecord{
+			Type:     "scan",
+			Vault:    report.Vault,
+			Notes:    report.Notes,
+			Total:    report.Total,
+			CacheHit: report.CacheHit,
+		}}
+		for _, t := range report.Tags {
+			records = append(records, tagRecord{Type: "tag", tagStat: t})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		rows := make([][]string, 0, len(report.Tags))
+		for _, t := range report.Tags {
+			rows = append(rows, []string{
+				t.Tag,
+				strconv.Itoa(t.Count),
+				strconv.FormatFloat(t.Relative, 'f', -1, 64),
+			})
+		}
+		return writeTable(w, opts.format, []string{"tag", "count", "relative"}, rows)
+	}
+
+	return nil
+}
+
+func (tc tagCounts) report(info scanInfo, limit int) tagReport {
+	names := tc.ranked(limit)
+
+	tags := make([]tagStat, 0, len(names))
+	for _, n := range names {
+		tags = append(tags, tagStat{
+			Tag:      n,
+			Count:    tc.Tags[n],
+			Relative: float64(tc.Tags[n]) / float64(tc.Total) * 100,
+		})
+	}
+
+	return tagReport{
+		Vault:    info.Vault,
+		Notes:    info.Notes,
+		Total:    tc.Total,
+		CacheHit: info.CacheHit,
+		Tags:     tags,
+	}
+}
+
+// scanRecord is the first record of the ndjson format and holds the scan
+// metadata.
+type scanRecord struct {
+	Type     string `json:"type"`
+	Vault    string `json:"vault"`
+	Notes    int    `json:"notes"`
+	Total    int    `json:"total"`
+	CacheHit bool   `json:"cacheHit"`
+}
+
+type tagRecord struct {
+	Type string `json:"type"`
+	tagStat
+}
+
+// writeDocument writes doc as a single indented JSON or YAML document.
+func writeDocument(w io.Writer, format outputFormat, doc any) error {
+	if format == yamlFormat {
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// writeRecords writes records as newline-delimited JSON, one record per line.
+func writeRecords(w io.Writer, records []any) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTable writes a header row followed by rows, with fields separated by
+// commas in the csv format and by tabs in the tsv format. Tabular formats
+// carry no scan metadata.
+func writeTable(w io.Writer, format outputFormat, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if format == tsvFormat {
+		cw.Comma = '\t'
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_tagCounts_render(t *testing.T) {
+	tc := tagCounts{
+		Tags: map[string]int{
+			"rust":   2,
+			"golang": 1,
+			"python": 1,
+		},
+		Total: 4,
+	}
+
+	info := scanInfo{
+		Vault:    "/vault",
+		Notes:    3,
+		CacheHit: true,
+	}
+
+	testCases := []struct {
+		name     string
+		format   outputFormat
+		limit    int
+		expected string
+	}{
+		{
+			name:     "text",
+			format:   textFormat,
+			limit:    -1,
+			expected: "rust\ngolang\npython\n",
+		},
+		{
+			name:   "json",
+			format: jsonFormat,
+			limit:  2,
+			expected: `{
+  "vault": "/vault",
+  "notes": 3,
+  "total": 4,
+  "cacheHit": true,
+  "tags": [
+    {
+      "tag": "rust",
+      "count": 2,
+      "relative": 50
+    },
+    {
+      "tag": "golang",
+      "count": 1,
+      "relative": 25
+    }
+  ]
+}
+`,
+		},
+		{
+			name:   "ndjson",
+			format: ndjsonFormat,
+			limit:  2,
+			expected: `{"type":"scan","vault":"/vault","notes":3,"total":4,"cacheHit":true}
+{"type":"tag","tag":"rust","count":2,"relative":50}
+{"type":"tag","tag":"golang","count":1,"relative":25}
+`,
+		},
+		{
+			name:     "csv",
+			format:   csvFormat,
+			limit:    -1,
+			expected: "tag,count,relative\nrust,2,50\ngolang,1,25\npython,1,25\n",
+		},
+		{
+			name:     "tsv",
+			format:   tsvFormat,
+			limit:    1,
+			expected: "tag\tcount\trelative\nrust\t2\t50\n",
+		},
+		{
+			name:   "yaml",
+			format: yamlFormat,
+			limit:  1,
+			expected: `vault: /vault
+notes: 3
+total: 4
+cacheHit: true
+tags:
+- tag: rust
+  count: 2
+  relative: 50.0
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			var buf strings.Builder
+			opts := rootOptions{
+				limit:  tt.limit,
+				format: tt.format,
+			}
+
+			r.NoError(tc.render(&buf, info, opts))
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func Test_tagCounts_render_empty(t *testing.T) {
+	tc := tagCounts{}
+
+	testCases := []struct {
+		name     string
+		format   outputFormat
+		expected string
+	}{
+		{
+			name:     "json",
+			format:   jsonFormat,
+			expected: "{\n  \"vault\": \"\",\n  \"notes\": 0,\n  \"total\": 0,\n  \"cacheHit\": false,\n  \"tags\": []\n}\n",
+		},
+		{
+			name:     "csv",
+			format:   csvFormat,
+			expected: "tag,count,relative\n",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			var buf strings.Builder
+			opts := rootOptions{format: tt.format}
+
+			r.NoError(tc.render(&buf, scanInfo{}, opts))
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+type graphFormat enumflag.Flag
+
+const (
+	dotFormat graphFormat = iota
+	graphMLFormat
+	mermaidFormat
+)
+
+var graphFormatIDs = map[graphFormat][]string{
+	dotFormat:     {"dot", "graphviz"},
+	graphMLFormat: {"graphml"},
+	mermaidFormat: {"mermaid"},
+}
+
+func graphFormatUsage() string {
+	v := slices.Collect(enumVariants(graphFormatIDs))
+	return fmt.Sprintf("graph format (%s)", strings.Join(v, "|"))
+}
+
+type graphOptions struct {
+	format       graphFormat
+	cooccurrence bool
+	minCount     int
+	minNotes     int
+	minJaccard   float64
+	scan         scanOptions
+}
+
+func newExportGraphCmd(version string) *cobra.Command {
+	var opts graphOptions
+
+	cmd := &cobra.Command{
+		Use:   "graph [path]",
+		Short: "Export the tag hierarchy and co-occurrence network as a graph",
+		Args:  cobra.RangeArgs(0, 1),
+		Example: `
+		# render the tag hierarchy with Graphviz
+		tobi export graph /path/to/your/vault | dot -Tsvg > tags.svg
+
+		# include tags used together in at least 3 notes, for Gephi
+		tobi export graph --cooccurrence --min-notes 3 --format graphml > tags.graphml
+
+		# embed in a Markdown note
+		tobi export graph --format mermaid --min-count 5
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			g := newTagGraph(res, opts)
+			return g.render(cmd.OutOrStdout(), opts.format)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.VarP(
+		enumflag.New(&opts.format, "format", graphFormatIDs, enumflag.EnumCaseSensitive),
+		"format", "f", graphFormatUsage(),
+	)
+	flags.BoolVarP(&opts.cooccurrence, "cooccurrence", "c", false, "add edges between tags used together in the same notes")
+	flags.IntVar(&opts.minCount, "min-count", 1, "only tags used at least this many times, including nested tags")
+	flags.IntVar(&opts.minNotes, "min-notes", 1, "only co-occurrence edges between tags carried together by at least this many notes")
+	flags.Float64Var(&opts.minJaccard, "min-jaccard", 0, "only co-occurrence edges with at least this Jaccard similarity, from 0 to 1")
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// graphNode is a tag in the tag graph.
+type graphNode struct {
+	// Tag is the full tag, which identifies the node.
+	Tag string
+	// Name is the last segment of the tag.
+	Name string
+	// Count is the number of usages of exactly this tag.
+	Count int
+	// Total is the number of usages of this tag and all of its descendants.
+	Total int
+}
+
+type edgeKind string
+
+const (
+	// childEdge goes from a tag to a tag nested directly under it.
+	childEdge edgeKind = "child"
+	// cooccurrenceEdge links two tags carried together by some notes.
+	cooccurrenceEdge edgeKind = "cooccurrence"
+)
+
+// graphEdge is a relation between two tags in the tag graph.
+type graphEdge struct {
+	From, To string
+	Kind     edgeKind
+	// Weight is the total count of the child for child edges, and the number
+	// of notes carrying both tags for co-occurrence edges.
+	Weight int
+}
+
+// tagGraph is the graph of the tags of a vault. Nodes are sorted like in tree
+// mode, parents first, and edges are sorted with child edges first.
+type tagGraph struct {
+	Nodes []graphNode
+	Edges []graphEdge
+}
+
+// newTagGraph builds the tag graph of res. Tags used less than opts.minCount
+// times, including nested tags, are pruned along with their edges.
+func newTagGraph(res *vault.Result, opts graphOptions) tagGraph {
+	var g tagGraph
+	kept := make(map[string]bool)
+
+	var walk func(n *tagx.TagNode)
+	walk = func(n *tagx.TagNode) {
+		for _, c := range n.Children {
+			if c.Total < opts.minCount {
+				continue
+			}
+
+			g.Nodes = append(g.Nodes, graphNode{Tag: c.Tag, Name: c.Name, Count: c.Count, Total: c.Total})
+			kept[c.Tag] = true
+			if n.Tag != "" {
+				g.Edges = append(g.Edges, graphEdge{From: n.Tag, To: c.Tag, Kind: childEdge, Weight: c.Total})
+			}
+			walk(c)
+		}
+	}
+	walk(tagx.NewTagTree(res.Counts))
+
+	if opts.cooccurrence {
+		for _, p := range vault.NewCooccurrence(res.Notes).Pairs(opts.minNotes) {
+			if !kept[p.A] || !kept[p.B] || p.Jaccard < opts.minJaccard {
+				continue
+			}
+			g.Edges = append(g.Edges, graphEdge{From: p.A, To: p.B, Kind: cooccurrenceEdge, Weight: p.Notes})
+		}
+	}
+
+	return g
+}
+
+func (g tagGraph) render(w io.Writer, format graphFormat) error {
+	switch format {
+	case dotFormat:
+		return g.writeDOT(w)
+	case graphMLFormat:
+		return g.writeGraphML(w)
+	case mermaidFormat:
+		return g.writeMermaid(w)
+	}
+	return nil
+}
+
+// writeDOT writes the graph in the Graphviz DOT language. Co-occurrence edges
+// are undirected and dashed.
+func (g tagGraph) writeDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph tags {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, count=%d, total=%d];\n",
+			strconv.Quote(n.Tag), strconv.Quote(fmt.Sprintf("%s (%d)", n.Name, n.Total)), n.Count, n.Total)
+	}
+	for _, e := range g.Edges {
+		attrs := fmt.Sprintf("weight=%d", e.Weight)
+		if e.Kind == cooccurrenceEdge {
+			attrs = fmt.Sprintf("dir=none, style=dashed, label=%q, weight=%d", strconv.Itoa(e.Weight), e.Weight)
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), attrs)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed bool          `xml:"directed,attr"`
+	Data     []graphMLData `xml:"data"`
+}
+
+// writeGraphML writes the graph in GraphML, as read by Gephi, yEd or
+// NetworkX. Co-occurrence edges are undirected.
+func (g tagGraph) writeGraphML(w io.Writer) error {
+	var doc graphMLDoc
+	doc.XMLNS = "http://graphml.graphdrawing.org/xmlns"
+	doc.Keys = []graphMLKey{
+		{ID: "name", For: "node", Name: "name", Type: "string"},
+		{ID: "count", For: "node", Name: "count", Type: "int"},
+		{ID: "total", For: "node", Name: "total", Type: "int"},
+		{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+		{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+	}
+	doc.Graph.ID = "tags"
+	doc.Graph.EdgeDefault = "directed"
+
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.Tag,
+			Data: []graphMLData{
+				{Key: "name", Value: n.Name},
+				{Key: "count", Value: strconv.Itoa(n.Count)},
+				{Key: "total", Value: strconv.Itoa(n.Total)},
+			},
+		})
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source:   e.From,
+			Target:   e.To,
+			Directed: e.Kind == childEdge,
+			Data: []graphMLData{
+				{Key: "kind", Value: string(e.Kind)},
+				{Key: "weight", Value: strconv.Itoa(e.Weight)},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart. Nodes get generated
+// ids, since tags may contain characters Mermaid does not allow in ids.
+// Co-occurrence edges are dotted and labeled with their weight.
+func (g tagGraph) writeMermaid(w io.Writer) error {
+	ids := make(map[string]string, len(g.Nodes))
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		id := "t" + strconv.Itoa(i)
+		ids[n.Tag] = id
+		fmt.Fprintf(&b, "  %s[\"%s (%d)\"]\n", id, mermaidEscape(n.Name), n.Total)
+	}
+	for _, e := range g.Edges {
+		if e.Kind == cooccurrenceEdge {
+			fmt.Fprintf(&b, "  %s -. %d .- %s\n", ids[e.From], e.Weight, ids[e.To])
+			continue
+		}
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscape escapes the characters of s that would end a quoted Mermaid
+// label.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
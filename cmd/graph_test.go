@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_exportGraphCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFiles(map[string]string{
+		"a.md": "#golang #golang/cobra",
+		"b.md": "#golang/cobra #cli",
+		"c.md": "#golang/cobra #cli",
+		"d.md": "#rust",
+	}))
+	defer dir.Remove()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "dot",
+			args: []string{},
+			expected: `digraph tags {
+  rankdir=LR;
+  node [shape=box];
+  "golang" [label="golang (4)", count=1, total=4];
+  "golang/cobra" [label="cobra (3)", count=3, total=3];
+  "cli" [label="cli (2)", count=2, total=2];
+  "rust" [label="rust (1)", count=1, total=1];
+  "golang" -> "golang/cobra" [weight=3];
+}
+`,
+		},
+		{
+			name: "dot with cooccurrence",
+			args: []string{"--cooccurrence", "--min-notes", "2", "--min-count", "2"},
+			expected: `digraph tags {
+  rankdir=LR;
+  node [shape=box];
+  "golang" [label="golang (4)", count=1, total=4];
+  "golang/cobra" [label="cobra (3)", count=3, total=3];
+  "cli" [label="cli (2)", count=2, total=2];
+  "golang" -> "golang/cobra" [weight=3];
+  "cli" -> "golang/cobra" [dir=none, style=dashed, label="2", weight=2];
+}
+`,
+		},
+		{
+			name: "graphml",
+			args: []string{"--format", "graphml", "--min-count", "3"},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="name" for="node" attr.name="name" attr.type="string"></key>
+  <key id="count" for="node" attr.name="count" attr.type="int"></key>
+  <key id="total" for="node" attr.name="total" attr.type="int"></key>
+  <key id="kind" for="edge" attr.name="kind" attr.type="string"></key>
+  <key id="weight" for="edge" attr.name="weight" attr.type="int"></key>
+  <graph id="tags" edgedefault="directed">
+    <node id="golang">
+      <data key="name">golang</data>
+      <data key="count">1</data>
+      <data key="total">4</data>
+    </node>
+    <node id="golang/cobra">
+      <data key="name">cobra</data>
+      <data key="count">3</data>
+      <data key="total">3</data>
+    </node>
+    <edge source="golang" target="golang/cobra" directed="true">
+      <data key="kind">child</data>
+      <data key="weight">3</data>
+    </edge>
+  </graph>
+</graphml>
+`,
+		},
+		{
+			name: "mermaid",
+			args: []string{"--format", "mermaid", "--cooccurrence", "--min-jaccard", "0.5"},
+			expected: `flowchart LR
+  t0["golang (4)"]
+  t1["cobra (3)"]
+  t2["cli (2)"]
+  t3["rust (1)"]
+  t0 --> t1
+  t2 -. 2 .- t1
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"export", "graph", dir.Path()}, tt.args...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+var periodIDs = map[vault.Period][]string{
+	vault.EveryCommit: {"commit"},
+	vault.Daily:       {"day", "daily"},
+	vault.Weekly:      {"week", "weekly"},
+	vault.Monthly:     {"month", "monthly"},
+	vault.Yearly:      {"year", "yearly"},
+}
+
+func periodUsage() string {
+	v := slices.Collect(enumVariants(periodIDs))
+	return fmt.Sprintf("sample the last commit of every period (%s)", strings.Join(v, "|"))
+}
+
+type historyOptions struct {
+	vault  string
+	since  string
+	until  string
+	every  vault.Period
+	tags   []string
+	limit  int
+	format outputFormat
+	scan   scanOptions
+}
+
+func newHistoryCmd(version string) *cobra.Command {
+	var opts historyOptions
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show tag counts over the git history of a vault",
+		Args:  cobra.NoArgs,
+		Example: `
+		# weekly sparklines of the top tags over the last year
+		tobi history --since 1y --every week --vault /path/to/your/vault
+
+		# monthly counts of two tags as CSV
+		tobi history --every month --tag golang --tag rust --format csv
+		`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var vaultArgs []string
+			if opts.vault != "" {
+				vaultArgs = append(vaultArgs, opts.vault)
+			}
+			root, err := vaultFromArgs(vaultArgs)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			var hr vault.HistoryRange
+			hr.Every = opts.every
+			if hr.Since, err = parseTimeFlag(opts.since, now, false); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if hr.Until, err = parseTimeFlag(opts.until, now, true); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+
+			snapshots, err := vault.History(cmd.Context(), root, hr,
+				vault.WithVersion(version),
+				vault.WithJobs(opts.scan.jobs),
+				vault.WithExcludeFile(),
+			)
+			if err != nil {
+				return err
+			}
+
+			tags := make([]string, 0, len(opts.tags))
+			for _, t := range opts.tags {
+				tags = append(tags, strings.TrimPrefix(t, "#"))
+			}
+			if len(tags) == 0 {
+				tags = topTags(snapshots, opts.limit)
+			}
+
+			ts := tagSeries{Vault: root, Every: periodIDs[opts.every][0], Tags: tags}
+			for _, s := range snapshots {
+				ts.Points = append(ts.Points, newSeriesPoint(s, tags, opts.every))
+			}
+			return ts.render(cmd.OutOrStdout(), opts.format)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&opts.vault, "vault", "V", "", "path to the vault. Defaults to OBSIDIAN_VAULT_PATH.")
+	flags.StringVar(&opts.since, "since", "", "only commits since a date (2006-01-02 or RFC 3339), or a duration ago (e.g. 30d, 12w, 6m, 1y)")
+	flags.StringVar(&opts.until, "until", "", "only commits until a date (2006-01-02 or RFC 3339), or a duration ago (e.g. 30d, 12w, 6m, 1y)")
+	flags.Var(
+		enumflag.New(&opts.every, "period", periodIDs, enumflag.EnumCaseSensitive),
+		"every", periodUsage(),
+	)
+	flags.StringArrayVarP(&opts.tags, "tag", "t", nil, "tag to include, may be repeated. Defaults to the most used tags.")
+	flags.IntVarP(&opts.limit, "limit", "l", 8, "number of top tags to include without --tag. Non-positive values mean all.")
+	addFormatFlag(cmd, &opts.format)
+	addJobsFlag(cmd, &opts.scan)
+
+	return cmd
+}
+
+var relativeTime = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+// parseTimeFlag parses s as a date, an RFC 3339 timestamp, or a duration ago
+// relative to now, such as 30d, 12w, 6m or 1y. A date is the start of the day
+// in UTC, or its end if end is true, so that date ranges are inclusive. An
+// empty s is the zero time.
+func parseTimeFlag(s string, now time.Time, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if m := relativeTime.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch m[2] {
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		case "m":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.New("expected a date, an RFC 3339 timestamp, or a duration such as 30d")
+	}
+	return t, nil
+}
+
+// topTags returns the tags used in any snapshot, sorted by count in the last
+// snapshot in descending order, then by peak count over all snapshots, then by
+// name. Non-positive values of limit mean all.
+func topTags(snapshots []vault.Snapshot, limit int) []string {
+	if len(snapshots) == 0 {
+		return []string{}
+	}
+
+	peak := make(map[string]int)
+	for _, s := range snapshots {
+		for t, n := range s.Counts {
+			peak[t] = max(peak[t], n)
+		}
+	}
+
+	last := snapshots[len(snapshots)-1].Counts
+	tags := slices.SortedFunc(maps.Keys(peak), func(a, b string) int {
+		return cmp.Or(
+			cmp.Compare(last[b], last[a]),
+			cmp.Compare(peak[b], peak[a]),
+			cmp.Compare(a, b),
+		)
+	})
+	if limit > 0 {
+		tags = tags[:min(len(tags), limit)]
+	}
+	return tags
+}
+
+// seriesPoint holds the counts of the selected tags as of a sampled commit.
+type seriesPoint struct {
+	// Period is the label of the sampled period, or the time of the commit
+	// when every commit is sampled.
+	Period string `json:"period" yaml:"period"`
+	Commit string `json:"commit" yaml:"commit"`
+	Time   string `json:"time" yaml:"time"`
+	Notes  int    `json:"notes" yaml:"notes"`
+	// Total is the number of occurrences of all tags, selected or not.
+	Total  int            `json:"total" yaml:"total"`
+	Counts map[string]int `json:"counts" yaml:"counts"`
+}
+
+func newSeriesPoint(s vault.Snapshot, tags []string, every vault.Period) seriesPoint {
+	counts := make(map[string]int, len(tags))
+	for _, t := range tags {
+		counts[t] = s.Counts[t]
+	}
+
+	return seriesPoint{
+		Period: periodLabel(s.Period, every),
+		Commit: s.Commit,
+		Time:   s.Time.Format(time.RFC3339),
+		Notes:  s.Notes,
+		Total:  s.Total,
+		Counts: counts,
+	}
+}
+
+// periodLabel formats the start of a period at the precision of the period.
+func periodLabel(t time.Time, every vault.Period) string {
+	switch every {
+	case vault.Daily, vault.Weekly:
+		return t.Format(time.DateOnly)
+	case vault.Monthly:
+		return t.Format("2006-01")
+	case vault.Yearly:
+		return t.Format("2006")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// tagSeries is the time series of the counts of the selected tags, oldest
+// first.
+type tagSeries struct {
+	Vault  string        `json:"vault" yaml:"vault"`
+	Every  string        `json:"every" yaml:"every"`
+	Tags   []string      `json:"tags" yaml:"tags"`
+	Points []seriesPoint `json:"points" yaml:"points"`
+}
+
+type pointRecord struct {
+	Type string `json:"type"`
+	seriesPoint
+}
+
+func (ts tagSeries) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		if len(ts.Points) == 0 {
+			_, err := fmt.Fprintln(w, "no commits")
+			return err
+		}
+
+		first, last := ts.Points[0], ts.Points[len(ts.Points)-1]
+		fmt.Fprintf(w, "%d snapshots from %s to %s\n", len(ts.Points), first.Period, last.Period)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, t := range ts.Tags {
+			values := make([]int, 0, len(ts.Points))
+			for _, p := range ts.Points {
+				values = append(values, p.Counts[t])
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d -> %d\n", t, sparkline(values), first.Counts[t], last.Counts[t])
+		}
+		return tw.Flush()
+	case jsonFormat, yamlFormat:
+		if ts.Points == nil {
+			ts.Points = []seriesPoint{}
+		}
+		return writeDocument(w, format, ts)
+	case ndjsonFormat:
+		records := make([]any, 0, len(ts.Points))
+		for _, p := range ts.Points {
+			records = append(records, pointRecord{Type: "point", seriesPoint: p})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		header := append([]string{"period", "commit", "notes", "total"}, ts.Tags...)
+		rows := make([][]string, 0, len(ts.Points))
+		for _, p := range ts.Points {
+			row := []string{p.Period, p.Commit, strconv.Itoa(p.Notes), strconv.Itoa(p.Total)}
+			for _, t := range ts.Tags {
+				row = append(row, strconv.Itoa(p.Counts[t]))
+			}
+			rows = append(rows, row)
+		}
+		return writeTable(w, format, header, rows)
+	}
+
+	return nil
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders values as a line of block characters, scaled from zero
+// to the largest value, so that lines of different tags compare in shape.
+// Zero values are rendered as spaces.
+func sparkline(values []int) string {
+	hi := 0
+	for _, v := range values {
+		hi = max(hi, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		if v <= 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		// 1..hi maps onto the len(sparks) levels
+		b.WriteRune(sparks[(v*len(sparks)-1)/hi])
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_parseTimeFlag(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		in   string
+		end  bool
+		want time.Time
+	}{
+		{name: "empty", in: "", want: time.Time{}},
+		{name: "date", in: "2024-01-02", want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "end of date", in: "2024-01-02", end: true, want: time.Date(2024, 1, 2, 23, 59, 59, 999999999, time.UTC)},
+		{name: "timestamp", in: "2024-01-02T10:00:00Z", end: true, want: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{name: "days ago", in: "30d", want: time.Date(2024, 5, 16, 12, 0, 0, 0, time.UTC)},
+		{name: "weeks ago", in: "2w", want: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "months ago", in: "6m", want: time.Date(2023, 12, 15, 12, 0, 0, 0, time.UTC)},
+		{name: "years ago", in: "1y", want: time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got, err := parseTimeFlag(tt.in, now, tt.end)
+			r.NoError(err)
+			r.Equal(tt.want, got)
+		})
+	}
+
+	for _, in := range []string{"yesterday", "2024-13-01", "1h", "-1d"} {
+		_, err := parseTimeFlag(in, now, false)
+		r.Error(err, in)
+	}
+}
+
+func Test_sparkline(t *testing.T) {
+	testCases := []struct {
+		name   string
+		values []int
+		want   string
+	}{
+		{name: "empty", values: nil, want: ""},
+		{name: "all zero", values: []int{0, 0}, want: "  "},
+		{name: "constant", values: []int{3, 3}, want: "██"},
+		{name: "increasing", values: []int{0, 1, 2, 4, 8}, want: " ▁▂▄█"},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.Equal(tt.want, sparkline(tt.values))
+		})
+	}
+}
+
+func Test_historyCmd(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test")
+	defer dir.Remove()
+
+	repo, err := git.PlainInit(dir.Path(), false)
+	r.NoError(err)
+	wt, err := repo.Worktree()
+	r.NoError(err)
+
+	when := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, content := range []string{"#golang", "#golang #rust", "#go #rust #rust"} {
+		r.NoError(os.WriteFile(filepath.Join(dir.Path(), "note.md"), []byte(content), 0o644))
+		_, err := wt.Add("note.md")
+		r.NoError(err)
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: when}
+		_, err = wt.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig})
+		r.NoError(err)
+		when = when.AddDate(0, 0, 1)
+	}
+
+	run := func(args ...string) string {
+		var buf strings.Builder
+		c := NewRootCmd("test")
+		c.SetOut(&buf)
+		c.SetArgs(append([]string{"history", "--vault", dir.Path()}, args...))
+		r.NoError(c.Execute())
+		return buf.String()
+	}
+
+	r.Equal(`3 snapshots from 2024-01-01 to 2024-01-03
+rust     ▄█  0 -> 2
+go        █  0 -> 1
+golang  ██   1 -> 0
+`, run("--every", "day", "--limit", "0"))
+
+	got := run("--every", "day", "--since", "2024-01-02", "--tag", "#rust", "--format", "csv")
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	r.Len(lines, 3)
+	r.Equal("period,commit,notes,total,rust", lines[0])
+	r.True(strings.HasPrefix(lines[1], "2024-01-02,"))
+	r.True(strings.HasSuffix(lines[1], ",1,2,1"))
+	r.True(strings.HasSuffix(lines[2], ",1,3,2"))
+
+	r.Equal("no commits\n", run("--since", "2030-01-01"))
+}
@@ -0,0 +1,535 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+// errLintIssues is returned by the lint command when issues are found.
+var errLintIssues = &statusError{err: errors.New("tag lint issues found"), code: exitFailure}
+
+// Checks of the lint command, in the order they are reported.
+const (
+	caseDuplicateCheck = "case-duplicate"
+	nearDuplicateCheck = "near-duplicate"
+	pluralCheck        = "plural"
+	singleUseCheck     = "single-use"
+	singleChildCheck   = "single-child"
+	namingCheck        = "naming"
+)
+
+var lintChecks = []string{
+	caseDuplicateCheck,
+	nearDuplicateCheck,
+	pluralCheck,
+	singleUseCheck,
+	singleChildCheck,
+	namingCheck,
+}
+
+// minNearDuplicateLength is the length under which tags are not compared for
+// near duplicates, since short tags such as ai and ml are often a single edit
+// apart.
+const minNearDuplicateLength = 4
+
+type namingStyle enumflag.Flag
+
+const (
+	anyStyle namingStyle = iota
+	lowerStyle
+	kebabStyle
+)
+
+var namingStyleIDs = map[namingStyle][]string{
+	anyStyle:   {"any"},
+	lowerStyle: {"lower", "lowercase"},
+	kebabStyle: {"kebab", "kebab-case"},
+}
+
+func namingStyleUsage() string {
+	v := slices.Collect(enumVariants(namingStyleIDs))
+	return fmt.Sprintf("naming style required of every tag (%s)", strings.Join(v, "|"))
+}
+
+// kebabSegment matches a segment of a kebab-case tag: lowercase words of
+// letters and digits separated by single hyphens.
+var kebabSegment = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}]+(-[\p{Ll}\p{Lo}\p{N}]+)*$`)
+
+type lintOptions struct {
+	maxDistance int
+	style       namingStyle
+	maxDepth    int
+	disable     []string
+	format      outputFormat
+	scan        scanOptions
+}
+
+func newLintCmd(version string) *cobra.Command {
+	var opts lintOptions
+
+	cmd := &cobra.Command{
+		Use:   "lint [path]",
+		Short: "Find inconsistent and near-duplicate tags",
+		Long: `Find inconsistent and near-duplicate tags.
+
+The checks are:
+  case-duplicate  tags that differ only in case
+  near-duplicate  tags a few edits apart, ignoring case and digits
+  plural          singular and plural forms of the same tag
+  single-use      tags used exactly once
+  single-child    tags only used as the parent of a single nested tag
+  naming          tags violating --style or --max-depth
+
+Exits with status 1 if any issue is found, and with status 2 on errors.`,
+		Args: cobra.RangeArgs(0, 1),
+		Example: `
+		# lint the tags of a vault
+		tobi lint /path/to/your/vault
+
+		# require kebab-case tags nested at most 3 levels deep
+		tobi lint --style kebab --max-depth 3
+
+		# fail in CI on duplicates only, with a JSON report
+		tobi lint --disable single-use,single-child --format json
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, c := range opts.disable {
+				if !slices.Contains(lintChecks, c) {
+					return fmt.Errorf("unknown check %q, must be one of %s", c, strings.Join(lintChecks, ", "))
+				}
+			}
+
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			report := lintReport{
+				Vault:  res.Root,
+				Notes:  len(res.Notes),
+				Tags:   len(res.Counts),
+				Issues: lintTags(res.Counts, opts),
+			}
+			if err := report.render(cmd.OutOrStdout(), opts.format); err != nil {
+				return err
+			}
+
+			if len(report.Issues) > 0 {
+				// not a usage error, the issues are the output
+				cmd.SilenceUsage = true
+				return errLintIssues
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.IntVar(&opts.maxDistance, "max-distance", 1, "maximum number of edits between near-duplicate tags. 0 disables the check.")
+	flags.Var(
+		enumflag.New(&opts.style, "style", namingStyleIDs, enumflag.EnumCaseSensitive),
+		"style", namingStyleUsage(),
+	)
+	flags.IntVar(&opts.maxDepth, "max-depth", 0, "maximum number of segments of a tag. Non-positive values mean unlimited.")
+	flags.StringSliceVar(&opts.disable, "disable", nil, fmt.Sprintf("checks to skip (%s)", strings.Join(lintChecks, "|")))
+	addFormatFlag(cmd, &opts.format)
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
+
+	if err := cmd.RegisterFlagCompletionFunc("style", completeNamingStyleFlag); err != nil {
+		os.Exit(1)
+	}
+	if err := cmd.RegisterFlagCompletionFunc("disable", completeLintCheckFlag); err != nil {
+		os.Exit(1)
+	}
+
+	reportsStatus(cmd)
+
+	return cmd
+}
+
+func completeNamingStyleFlag(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return slices.Collect(enumAliases(namingStyleIDs)), cobra.ShellCompDirectiveDefault
+}
+
+func completeLintCheckFlag(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return lintChecks, cobra.ShellCompDirectiveNoFileComp
+}
+
+// lintIssue is an issue found by a check of the lint command.
+type lintIssue struct {
+	Check string `json:"check" yaml:"check"`
+	// Tags are the tags involved, most used first for duplicates.
+	Tags    []string `json:"tags" yaml:"tags"`
+	Message string   `json:"message" yaml:"message"`
+}
+
+// lintTags runs the checks not disabled in opts on the tags in counts, and
+// returns the issues found, grouped by check in the order of lintChecks, then
+// sorted by tags.
+func lintTags(counts map[string]int, opts lintOptions) []lintIssue {
+	l := newTagLinter(counts)
+
+	var issues []lintIssue
+	for _, c := range lintChecks {
+		if slices.Contains(opts.disable, c) {
+			continue
+		}
+		switch c {
+		case caseDuplicateCheck:
+			issues = append(issues, l.caseDuplicates()...)
+		case nearDuplicateCheck:
+			issues = append(issues, l.nearDuplicates(opts.maxDistance)...)
+		case pluralCheck:
+			issues = append(issues, l.plurals()...)
+		case singleUseCheck:
+			issues = append(issues, l.singleUses()...)
+		case singleChildCheck:
+			issues = append(issues, l.singleChildren()...)
+		case namingCheck:
+			issues = append(issues, l.naming(opts.style, opts.maxDepth)...)
+		}
+	}
+
+	return issues
+}
+
+// tagLinter holds the tags of a vault, grouped by case-folded form.
+type tagLinter struct {
+	counts map[string]int
+	// tags are sorted.
+	tags []string
+	// folds are the sorted case-folded forms of the tags.
+	folds []string
+	// byFold are the tags of every case-folded form, most used first.
+	byFold map[string][]string
+	// pluralPairs are the pairs of case-folded forms that are singular and plural
+	// forms of each other, singular first.
+	pluralPairs map[[2]string]bool
+}
+
+func newTagLinter(counts map[string]int) *tagLinter {
+	l := &tagLinter{
+		counts:      counts,
+		tags:        slices.Sorted(maps.Keys(counts)),
+		byFold:      make(map[string][]string),
+		pluralPairs: make(map[[2]string]bool),
+	}
+
+	for _, t := range l.tags {
+		f := strings.ToLower(t)
+		l.byFold[f] = append(l.byFold[f], t)
+	}
+	for f, tags := range l.byFold {
+		l.sortByCount(tags)
+		l.folds = append(l.folds, f)
+	}
+	slices.Sort(l.folds)
+
+	for _, f := range l.folds {
+		for _, p := range pluralForms(f) {
+			if _, ok := l.byFold[p]; ok {
+				l.pluralPairs[[2]string{f, p}] = true
+			}
+		}
+	}
+
+	return l
+}
+
+// sortByCount sorts tags by count in descending order, then by name.
+func (l *tagLinter) sortByCount(tags []string) {
+	slices.SortFunc(tags, func(a, b string) int {
+		return cmp.Or(cmp.Compare(l.counts[b], l.counts[a]), cmp.Compare(a, b))
+	})
+}
+
+// describe returns tags with their counts, as in "golang (12) and Golang (1)".
+func (l *tagLinter) describe(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, t := range tags {
+		parts[i] = fmt.Sprintf("%s (%d)", t, l.counts[t])
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// pair returns the most used tags of the case-folded forms a and b, most used
+// first.
+func (l *tagLinter) pair(a, b string) []string {
+	tags := []string{l.byFold[a][0], l.byFold[b][0]}
+	l.sortByCount(tags)
+	return tags
+}
+
+func (l *tagLinter) caseDuplicates() []lintIssue {
+	var issues []lintIssue
+	for _, f := range l.folds {
+		tags := l.byFold[f]
+		if len(tags) < 2 {
+			continue
+		}
+		issues = append(issues, lintIssue{
+			Check:   caseDuplicateCheck,
+			Tags:    tags,
+			Message: l.describe(tags) + " differ only in case",
+		})
+	}
+	return issues
+}
+
+// nearDuplicates returns the pairs of tags at most maxDistance edits apart,
+// ignoring case. Tags that differ only in their digits, such as year/2023 and
+// year/2024, nested tags and their ancestors, and singular and plural forms
+// are not near duplicates.
+func (l *tagLinter) nearDuplicates(maxDistance int) []lintIssue {
+	if maxDistance <= 0 {
+		return nil
+	}
+
+	var issues []lintIssue
+	for i, a := range l.folds {
+		ra := []rune(a)
+		for _, b := range l.folds[i+1:] {
+			rb := []rune(b)
+			if min(len(ra), len(rb)) < minNearDuplicateLength || abs(len(ra)-len(rb)) > maxDistance {
+				continue
+			}
+			if l.pluralPairs[[2]string{a, b}] || l.pluralPairs[[2]string{b, a}] ||
+				tagx.IsDescendant(a, b) || tagx.IsDescendant(b, a) ||
+				stripDigits(a) == stripDigits(b) {
+				continue
+			}
+
+			d := editDistance(ra, rb, maxDistance)
+			if d > maxDistance {
+				continue
+			}
+
+			edits := "edits"
+			if d == 1 {
+				edits = "edit"
+			}
+			tags := l.pair(a, b)
+			issues = append(issues, lintIssue{
+				Check:   nearDuplicateCheck,
+				Tags:    tags,
+				Message: fmt.Sprintf("%s are %d %s apart", l.describe(tags), d, edits),
+			})
+		}
+	}
+	return issues
+}
+
+func (l *tagLinter) plurals() []lintIssue {
+	var issues []lintIssue
+	for _, f := range l.folds {
+		for _, p := range pluralForms(f) {
+			if !l.pluralPairs[[2]string{f, p}] {
+				continue
+			}
+			tags := l.pair(f, p)
+			issues = append(issues, lintIssue{
+				Check:   pluralCheck,
+				Tags:    tags,
+				Message: l.describe(tags) + " are singular and plural forms",
+			})
+		}
+	}
+	return issues
+}
+
+func (l *tagLinter) singleUses() []lintIssue {
+	var issues []lintIssue
+	for _, t := range l.tags {
+		if l.counts[t] != 1 {
+			continue
+		}
+		issues = append(issues, lintIssue{
+			Check:   singleUseCheck,
+			Tags:    []string{t},
+			Message: t + " is used once",
+		})
+	}
+	return issues
+}
+
+// singleChildren returns the tags never used on their own, with a single
+// nested tag, such as golang when golang/cobra is the only tag nested under
+// it.
+func (l *tagLinter) singleChildren() []lintIssue {
+	var issues []lintIssue
+
+	var walk func(n *tagx.TagNode)
+	walk = func(n *tagx.TagNode) {
+		for _, c := range n.Children {
+			if c.Count == 0 && len(c.Children) == 1 {
+				child := c.Children[0].Tag
+				issues = append(issues, lintIssue{
+					Check:   singleChildCheck,
+					Tags:    []string{c.Tag, child},
+					Message: fmt.Sprintf("%s is only used as the parent of %s", c.Tag, child),
+				})
+			}
+			walk(c)
+		}
+	}
+	walk(tagx.NewTagTree(l.counts))
+
+	slices.SortFunc(issues, func(a, b lintIssue) int {
+		return strings.Compare(a.Tags[0], b.Tags[0])
+	})
+	return issues
+}
+
+// naming returns the tags not written in style, or with more than maxDepth
+// segments if maxDepth is positive.
+func (l *tagLinter) naming(style namingStyle, maxDepth int) []lintIssue {
+	var issues []lintIssue
+	for _, t := range l.tags {
+		switch style {
+		case lowerStyle:
+			if t != strings.ToLower(t) {
+				issues = append(issues, lintIssue{Check: namingCheck, Tags: []string{t}, Message: t + " is not lowercase"})
+			}
+		case kebabStyle:
+			for seg := range strings.SplitSeq(t, "/") {
+				if !kebabSegment.MatchString(seg) {
+					issues = append(issues, lintIssue{Check: namingCheck, Tags: []string{t}, Message: t + " is not kebab-case"})
+					break
+				}
+			}
+		}
+
+		if depth := strings.Count(t, "/") + 1; maxDepth > 0 && depth > maxDepth {
+			issues = append(issues, lintIssue{
+				Check:   namingCheck,
+				Tags:    []string{t},
+				Message: fmt.Sprintf("%s is nested %d levels deep, more than %d", t, depth, maxDepth),
+			})
+		}
+	}
+	return issues
+}
+
+// pluralForms returns the English plural forms of the lowercase tag s: with
+// an s, with es, and with ies for a trailing y after a consonant.
+func pluralForms(s string) []string {
+	forms := []string{s + "s", s + "es"}
+	if n := len(s); n >= 2 && s[n-1] == 'y' && !strings.ContainsRune("aeiou", rune(s[n-2])) {
+		forms = append(forms, s[:n-1]+"ies")
+	}
+	return forms
+}
+
+// stripDigits returns s without its digits.
+func stripDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the number of insertions, deletions, substitutions and transpositions of
+// adjacent runes turning a into b. Distances over maxDistance are not computed
+// exactly, and any value over maxDistance is returned.
+func editDistance(a, b []rune, maxDistance int) int {
+	// rows i-2, i-1 and i of the distance matrix
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > maxDistance {
+			return rowMin
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(b)]
+}
+
+// lintReport is the document written by the json and yaml formats.
+type lintReport struct {
+	Vault string `json:"vault" yaml:"vault"`
+	Notes int    `json:"notes" yaml:"notes"`
+	// Tags is the number of distinct tags.
+	Tags   int         `json:"tags" yaml:"tags"`
+	Issues []lintIssue `json:"issues" yaml:"issues"`
+}
+
+type lintRecord struct {
+	Type string `json:"type"`
+	lintIssue
+}
+
+func (r lintReport) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, i := range r.Issues {
+			fmt.Fprintf(tw, "%s\t%s\n", i.Check, i.Message)
+		}
+		return tw.Flush()
+	case jsonFormat, yamlFormat:
+		if r.Issues == nil {
+			r.Issues = []lintIssue{}
+		}
+		return writeDocument(w, format, r)
+	case ndjsonFormat:
+		records := make([]any, 0, len(r.Issues))
+		for _, i := range r.Issues {
+			records = append(records, lintRecord{Type: "issue", lintIssue: i})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		rows := make([][]string, 0, len(r.Issues))
+		for _, i := range r.Issues {
+			rows = append(rows, []string{i.Check, strings.Join(i.Tags, " "), i.Message})
+		}
+		return writeTable(w, format, []string{"check", "tags", "message"}, rows)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_lintCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("a.md", "#javascript #javascript #JavaScript #golang #golang #golnag"),
+		fs.WithFile("b.md", "#book #books #books #library #libraries #year/2023 #year/2024"),
+		fs.WithFile("c.md", "#lang/go/Std #lang/go/Std #year/2023 #year/2024 #books"),
+		fs.WithFile("d.md", "#draft"),
+		fs.WithFile(".tobi.exclude", "draft"),
+	)
+	defer dir.Remove()
+
+	clean := fs.NewDir(t, "test",
+		fs.WithFile("a.md", "#golang #golang/cobra"),
+		fs.WithFile("b.md", "#golang #golang/cobra"),
+	)
+	defer clean.Remove()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		wantErr  error
+	}{
+		{
+			name: "all checks",
+			args: []string{dir.Path()},
+			expected: `case-duplicate  javascript (2) and JavaScript (1) differ only in case
+near-duplicate  golang (2) and golnag (1) are 1 edit apart
+plural          books (3) and book (1) are singular and plural forms
+plural          libraries (1) and library (1) are singular and plural forms
+single-use      JavaScript is used once
+single-use      book is used once
+single-use      golnag is used once
+single-use      libraries is used once
+single-use      library is used once
+single-child    lang is only used as the parent of lang/go
+single-child    lang/go is only used as the parent of lang/go/Std
+`,
+			wantErr: errLintIssues,
+		},
+		{
+			name: "naming",
+			args: []string{dir.Path(), "--style", "kebab", "--max-depth", "2", "--disable", "case-duplicate,near-duplicate,plural,single-use,single-child"},
+			expected: `naming  JavaScript is not kebab-case
+naming  lang/go/Std is not kebab-case
+naming  lang/go/Std is nested 3 levels deep, more than 2
+`,
+			wantErr: errLintIssues,
+		},
+		{
+			name:     "lowercase",
+			args:     []string{dir.Path(), "--style", "lower", "--max-distance", "0", "--disable", "case-duplicate,plural,single-use,single-child"},
+			expected: "naming  JavaScript is not lowercase\nnaming  lang/go/Std is not lowercase\n",
+			wantErr:  errLintIssues,
+		},
+		{
+			name: "json",
+			args: []string{dir.Path(), "--format", "json", "--disable", "near-duplicate,plural,single-use,single-child"},
+			expected: `{
+  "vault": "` + dir.Path() + `",
+  "notes": 4,
+  "tags": 11,
+  "issues": [
+    {
+      "check": "case-duplicate",
+      "tags": [
+        "javascript",
+        "JavaScript"
+      ],
+      "message": "javascript (2) and JavaScript (1) differ only in case"
+    }
+  ]
+}
+`,
+			wantErr: errLintIssues,
+		},
+		{
+			name:     "no issues",
+			args:     []string{clean.Path(), "--style", "kebab"},
+			expected: "",
+		},
+		{
+			name: "no issues json",
+			args: []string{clean.Path(), "--format", "json"},
+			expected: `{
+  "vault": "` + clean.Path() + `",
+  "notes": 2,
+  "tags": 2,
+  "issues": []
+}
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetErr(&strings.Builder{})
+			c.SetArgs(append([]string{"lint"}, tt.args...))
+
+			err := c.Execute()
+			if tt.wantErr != nil {
+				r.ErrorIs(err, tt.wantErr)
+			} else {
+				r.NoError(err)
+			}
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func Test_lintCmd_ErrorCases(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFile("a.md", "#golang"))
+	defer dir.Remove()
+
+	r := require.New(t)
+
+	c := NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{"lint", dir.Path(), "--disable", "typos"})
+	r.ErrorContains(c.Execute(), `unknown check "typos"`)
+}
+
+func Test_editDistance(t *testing.T) {
+	testCases := []struct {
+		name        string
+		a, b        string
+		maxDistance int
+		expected    int
+	}{
+		{name: "equal", a: "golang", b: "golang", maxDistance: 2, expected: 0},
+		{name: "substitution", a: "golang", b: "gulang", maxDistance: 2, expected: 1},
+		{name: "insertion", a: "golang", b: "go-lang", maxDistance: 2, expected: 1},
+		{name: "transposition", a: "golang", b: "golnag", maxDistance: 2, expected: 1},
+		{name: "several edits", a: "kitten", b: "sitting", maxDistance: 3, expected: 3},
+		{name: "empty", a: "", b: "go", maxDistance: 2, expected: 2},
+		{name: "runes", a: "café", b: "cafe", maxDistance: 1, expected: 1},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.Equal(tt.expected, editDistance([]rune(tt.a), []rune(tt.b), tt.maxDistance))
+		})
+	}
+
+	// distances over maxDistance are only bounded
+	r.Greater(editDistance([]rune("kubernetes"), []rune("k8s"), 1), 1)
+}
+
+func Test_pluralForms(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal([]string{"books", "bookes"}, pluralForms("book"))
+	r.Equal([]string{"librarys", "libraryes", "libraries"}, pluralForms("library"))
+	r.Equal([]string{"days", "dayes"}, pluralForms("day"))
+}
@@ -0,0 +1,449 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+// mcpProtocolVersions are the versions of the Model Context Protocol
+// supported by the server, latest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// tagsResourceURI identifies the resource holding every tag of the vault.
+const tagsResourceURI = "tobi://tags"
+
+// JSON-RPC error codes.
+const (
+	rpcParseError       = -32700
+	rpcInvalidRequest   = -32600
+	rpcMethodNotFound   = -32601
+	rpcInvalidParams    = -32602
+	rpcInternalError    = -32603
+	rpcResourceNotFound = -32002
+)
+
+type mcpOptions struct {
+	scan scanOptions
+}
+
+func newMCPCmd(version string) *cobra.Command {
+	var opts mcpOptions
+
+	cmd := &cobra.Command{
+		Use:   "mcp [path]",
+		Short: "Serve the tags of a vault to LLM clients over the Model Context Protocol",
+		Long: `Serve the tags of a vault to LLM clients over the Model Context Protocol.
+
+The server speaks JSON-RPC over stdin and stdout, and is meant to be started by
+an MCP client such as a desktop assistant or an editor.`,
+		Args: cobra.RangeArgs(0, 1),
+		Example: `
+		# in the configuration of an MCP client
+		{"mcpServers": {"tobi": {"command": "tobi", "args": ["mcp", "/path/to/your/vault"]}}}
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, err := openVault(root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			s := &mcpServer{sc: sc, version: version}
+			err = s.serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	addJobsFlag(cmd, &opts.scan)
+
+	return cmd
+}
+
+// mcpServer answers MCP requests about the tags of a vault. The vault is
+// scanned again for every request that needs it, which is fast since only
+// notes changed since the last scan are read, and unchanged notes are served
+// from the cache.
+type mcpServer struct {
+	sc      *vault.Scanner
+	version string
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	// ID is absent for notifications, which get no response.
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// serve reads newline-delimited JSON-RPC messages from r and writes responses
+// to w, one per line, until r is exhausted or ctx is done.
+func (s *mcpServer) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	// requests may carry the text of a whole note
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := sc.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp := rpcResponse{JSONRPC: "2.0", Error: &rpcError{rpcParseError, err.Error()}}
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, err := s.handle(ctx, req)
+		if req.ID == nil {
+			continue
+		}
+
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if err != nil {
+			var re *rpcError
+			if !errors.As(err, &re) {
+				re = &rpcError{rpcInternalError, err.Error()}
+			}
+			resp.Result, resp.Error = nil, re
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+
+	return sc.Err()
+}
+
+// handle returns the result of req. Errors are either *rpcError or internal
+// errors.
+func (s *mcpServer) handle(ctx context.Context, req rpcRequest) (any, error) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{rpcInvalidRequest, `jsonrpc must be "2.0"`}
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		// answer with the version requested by the client if supported
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities": map[string]any{
+				"tools":     map[string]any{},
+				"resources": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    "tobi",
+				"version": s.version,
+			},
+			"instructions": "Tools and resources describe the tags of the Obsidian vault " + s.sc.Root() +
+				". Prefer existing tags, and their hierarchy of nested tags, when tagging notes.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools := make([]map[string]any, 0, len(mcpTools))
+		for _, t := range mcpTools {
+			tools = append(tools, map[string]any{
+				"name":        t.name,
+				"description": t.description,
+				"inputSchema": t.inputSchema,
+			})
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		return s.callTool(ctx, req.Params)
+	case "resources/list":
+		return map[string]any{
+			"resources": []map[string]any{{
+				"uri":         tagsResourceURI,
+				"name":        "tags",
+				"description": "Every tag of the vault with its count, most used first",
+				"mimeType":    "application/json",
+			}},
+		}, nil
+	case "resources/read":
+		return s.readResource(ctx, req.Params)
+	}
+
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{rpcInvalidParams, err.Error()}
+	}
+	return nil
+}
+
+func (s *mcpServer) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(mcpTools, func(t mcpTool) bool { return t.name == p.Name })
+	if i < 0 {
+		return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+
+	res, err := s.sc.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := mcpTools[i].call(ctx, s.sc, res, p.Arguments)
+	if err != nil {
+		// tool errors are reported to the model rather than to the client
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(b)}},
+		"structuredContent": out,
+	}, nil
+}
+
+func (s *mcpServer) readResource(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.URI != tagsResourceURI {
+		return nil, &rpcError{rpcResourceNotFound, fmt.Sprintf("resource %q not found", p.URI)}
+	}
+
+	res, err := s.sc.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := tagCounts{Tags: res.Counts, Total: res.Total}
+	b, err := json.Marshal(tc.report(newScanInfo(res), 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"contents": []map[string]any{{
+			"uri":      tagsResourceURI,
+			"mimeType": "application/json",
+			"text":     string(b),
+		}},
+	}, nil
+}
+
+// mcpTool is a tool exposed to MCP clients.
+type mcpTool struct {
+	name        string
+	description string
+	inputSchema map[string]any
+	// call returns the structured result of the tool for the vault scanned by
+	// sc in res, given its JSON arguments. Errors are shown to the model.
+	call func(ctx context.Context, sc *vault.Scanner, res *vault.Result, args json.RawMessage) (any, error)
+}
+
+// objectSchema returns the JSON schema of an object with the given properties.
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func intSchema(desc string) map[string]any {
+	return map[string]any{"type": "integer", "description": desc}
+}
+
+func stringSchema(desc string) map[string]any {
+	return map[string]any{"type": "string", "description": desc}
+}
+
+func boolSchema(desc string) map[string]any {
+	return map[string]any{"type": "boolean", "description": desc}
+}
+
+// decodeArgs decodes the arguments of a tool into v.
+func decodeArgs(args json.RawMessage, v any) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// toolTag returns tag without its leading '#', or an error if it is invalid.
+func toolTag(tag string) (string, error) {
+	tag = strings.TrimPrefix(tag, "#")
+	if !tagx.IsValid(tag) {
+		return "", fmt.Errorf("invalid tag %q", tag)
+	}
+	return tag, nil
+}
+
+var mcpTools = []mcpTool{
+	{
+		name:        "list_tags",
+		description: "List the tags of the vault with their number of uses, most used first.",
+		inputSchema: objectSchema(map[string]any{
+			"prefix": stringSchema("only this tag and the tags nested under it, e.g. golang"),
+			"min":    intSchema("only tags used at least this many times"),
+			"limit":  intSchema("maximum number of tags. Non-positive values mean all."),
+		}),
+		call: func(_ context.Context, _ *vault.Scanner, res *vault.Result, args json.RawMessage) (any, error) {
+			var a struct {
+				Prefix string `json:"prefix"`
+				Min    int    `json:"min"`
+				Limit  int    `json:"limit"`
+			}
+			if err := decodeArgs(args, &a); err != nil {
+				return nil, err
+			}
+
+			tc := filterTags(res, strings.TrimPrefix(a.Prefix, "#"), a.Min)
+			return tc.report(newScanInfo(res), a.Limit), nil
+		},
+	},
+	{
+		name:        "tag_tree",
+		description: "Get the hierarchy of nested tags, such as golang/cobra under golang, with the uses of every tag and of its descendants.",
+		inputSchema: objectSchema(map[string]any{
+			"depth": intSchema("maximum depth of the tree. Non-positive values mean unlimited."),
+		}),
+		call: func(_ context.Context, _ *vault.Scanner, res *vault.Result, args json.RawMessage) (any, error) {
+			var a struct {
+				Depth int `json:"depth"`
+			}
+			if err := decodeArgs(args, &a); err != nil {
+				return nil, err
+			}
+
+			tree := tagx.NewTagTree(res.Counts)
+			if a.Depth > 0 {
+				pruneTree(tree, a.Depth)
+			}
+			return tree, nil
+		},
+	},
+	{
+		name:        "notes_for_tag",
+		description: "List the notes carrying a tag, by vault-relative path, with how many times they carry it.",
+		inputSchema: objectSchema(map[string]any{
+			"tag":         stringSchema("the tag, e.g. golang/cobra"),
+			"descendants": boolSchema("include notes carrying tags nested under the tag"),
+			"limit":       intSchema("maximum number of notes. Non-positive values mean all."),
+		}, "tag"),
+		call: func(_ context.Context, _ *vault.Scanner, res *vault.Result, args json.RawMessage) (any, error) {
+			var a struct {
+				Tag         string `json:"tag"`
+				Descendants bool   `json:"descendants"`
+				Limit       int    `json:"limit"`
+			}
+			if err := decodeArgs(args, &a); err != nil {
+				return nil, err
+			}
+			tag, err := toolTag(a.Tag)
+			if err != nil {
+				return nil, err
+			}
+
+			return newNotesReport(res, tag, a.Descendants, a.Limit), nil
+		},
+	},
+	{
+		name:        "suggest_tags_for_text",
+		description: "Suggest existing tags of the vault for a text, such as a new note, ranked by similarity with the notes carrying them and by co-occurrence with the tags the text already has. Only tags that already exist are suggested.",
+		inputSchema: objectSchema(map[string]any{
+			"text":  stringSchema("the text to tag"),
+			"limit": intSchema("maximum number of tags, 8 by default. Non-positive values mean all."),
+		}, "text"),
+		call: func(ctx context.Context, sc *vault.Scanner, res *vault.Result, args json.RawMessage) (any, error) {
+			a := struct {
+				Text  string `json:"text"`
+				Limit int    `json:"limit"`
+			}{Limit: 8}
+			if err := decodeArgs(args, &a); err != nil {
+				return nil, err
+			}
+
+			return suggestTags(ctx, sc, res, res.Notes, a.Text, a.Limit)
+		},
+	},
+}
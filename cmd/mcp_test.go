@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_mcpCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("a.md", "#golang #golang/cobra"),
+		fs.WithFile("b.md", "#golang/cobra #cli"),
+		fs.WithFile("c.md", "#rust #cli"),
+	)
+	defer dir.Remove()
+
+	// every request is answered on its own line, in order, except for
+	// notifications
+	testCases := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{
+			name:     "initialize",
+			request:  `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+			expected: `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26","capabilities":{"resources":{},"tools":{}},"serverInfo":{"name":"tobi","version":"test"},"instructions":"Tools and resources describe the tags of the Obsidian vault ` + dir.Path() + `. Prefer existing tags, and their hierarchy of nested tags, when tagging notes."}}`,
+		},
+		{
+			name:    "initialized",
+			request: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		},
+		{
+			name:     "ping",
+			request:  `{"jsonrpc":"2.0","id":"ping","method":"ping"}`,
+			expected: `{"jsonrpc":"2.0","id":"ping","result":{}}`,
+		},
+		{
+			name:     "list tags",
+			request:  `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"list_tags","arguments":{"prefix":"golang"}}}`,
+			expected: `{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"{\"vault\":\"` + dir.Path() + `\",\"notes\":3,\"total\":6,\"cacheHit\":false,\"tags\":[{\"tag\":\"golang/cobra\",\"count\":2,\"relative\":33.33333333333333},{\"tag\":\"golang\",\"count\":1,\"relative\":16.666666666666664}]}"}],"structuredContent":{"vault":"` + dir.Path() + `","notes":3,"total":6,"cacheHit":false,"tags":[{"tag":"golang/cobra","count":2,"relative":33.33333333333333},{"tag":"golang","count":1,"relative":16.666666666666664}]}}}`,
+		},
+		{
+			name:     "tag tree",
+			request:  `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"tag_tree","arguments":{"depth":1}}}`,
+			expected: `{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"{\"name\":\"\",\"tag\":\"\",\"count\":0,\"total\":6,\"children\":[{\"name\":\"golang\",\"tag\":\"golang\",\"count\":1,\"total\":3},{\"name\":\"cli\",\"tag\":\"cli\",\"count\":2,\"total\":2},{\"name\":\"rust\",\"tag\":\"rust\",\"count\":1,\"total\":1}]}"}],"structuredContent":{"name":"","tag":"","count":0,"total":6,"children":[{"name":"golang","tag":"golang","count":1,"total":3},{"name":"cli","tag":"cli","count":2,"total":2},{"name":"rust","tag":"rust","count":1,"total":1}]}}}`,
+		},
+		{
+			name:     "notes for tag",
+			request:  `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"notes_for_tag","arguments":{"tag":"#cli"}}}`,
+			expected: `{"jsonrpc":"2.0","id":4,"result":{"content":[{"type":"text","text":"{\"vault\":\"` + dir.Path() + `\",\"notes\":3,\"total\":2,\"cacheHit\":true,\"tag\":\"cli\",\"descendants\":false,\"matches\":[{\"path\":\"b.md\",\"count\":1},{\"path\":\"c.md\",\"count\":1}]}"}],"structuredContent":{"vault":"` + dir.Path() + `","notes":3,"total":2,"cacheHit":true,"tag":"cli","descendants":false,"matches":[{"path":"b.md","count":1},{"path":"c.md","count":1}]}}}`,
+		},
+		{
+			name:     "suggest tags for text",
+			request:  `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"suggest_tags_for_text","arguments":{"text":"A CLI written with Cobra.","limit":2}}}`,
+			expected: `{"jsonrpc":"2.0","id":5,"result":{"content":[{"type":"text","text":"{\"vault\":\"` + dir.Path() + `\",\"notes\":3,\"tags\":[],\"suggestions\":[{\"tag\":\"cli\",\"count\":2,\"score\":0.35213277480950167,\"similarity\":0.35213277480950167,\"cooccurrence\":0},{\"tag\":\"golang/cobra\",\"count\":2,\"score\":0.31445501422461497,\"similarity\":0.31445501422461497,\"cooccurrence\":0}]}"}],"structuredContent":{"vault":"` + dir.Path() + `","notes":3,"tags":[],"suggestions":[{"tag":"cli","count":2,"score":0.35213277480950167,"similarity":0.35213277480950167,"cooccurrence":0},{"tag":"golang/cobra","count":2,"score":0.31445501422461497,"similarity":0.31445501422461497,"cooccurrence":0}]}}}`,
+		},
+		{
+			name:     "tool error",
+			request:  `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"notes_for_tag","arguments":{}}}`,
+			expected: `{"jsonrpc":"2.0","id":6,"result":{"content":[{"type":"text","text":"invalid tag \"\""}],"isError":true}}`,
+		},
+		{
+			name:     "unknown tool",
+			request:  `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"delete_tags"}}`,
+			expected: `{"jsonrpc":"2.0","id":7,"error":{"code":-32602,"message":"unknown tool \"delete_tags\""}}`,
+		},
+		{
+			name:     "read tags resource",
+			request:  `{"jsonrpc":"2.0","id":8,"method":"resources/read","params":{"uri":"tobi://tags"}}`,
+			expected: `{"jsonrpc":"2.0","id":8,"result":{"contents":[{"uri":"tobi://tags","mimeType":"application/json","text":"{\"vault\":\"` + dir.Path() + `\",\"notes\":3,\"total\":6,\"cacheHit\":true,\"tags\":[{\"tag\":\"cli\",\"count\":2,\"relative\":33.33333333333333},{\"tag\":\"golang/cobra\",\"count\":2,\"relative\":33.33333333333333},{\"tag\":\"golang\",\"count\":1,\"relative\":16.666666666666664},{\"tag\":\"rust\",\"count\":1,\"relative\":16.666666666666664}]}"}]}}`,
+		},
+		{
+			name:     "unknown resource",
+			request:  `{"jsonrpc":"2.0","id":9,"method":"resources/read","params":{"uri":"tobi://notes"}}`,
+			expected: `{"jsonrpc":"2.0","id":9,"error":{"code":-32002,"message":"resource \"tobi://notes\" not found"}}`,
+		},
+		{
+			name:     "unknown method",
+			request:  `{"jsonrpc":"2.0","id":10,"method":"prompts/list"}`,
+			expected: `{"jsonrpc":"2.0","id":10,"error":{"code":-32601,"message":"method \"prompts/list\" not found"}}`,
+		},
+		{
+			name:     "parse error",
+			request:  `{"jsonrpc":`,
+			expected: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`,
+		},
+	}
+
+	var in strings.Builder
+	var expected []string
+	for _, tt := range testCases {
+		in.WriteString(tt.request + "\n")
+		if tt.expected != "" {
+			expected = append(expected, tt.expected)
+		}
+	}
+
+	r := require.New(t)
+
+	var out strings.Builder
+	c := NewRootCmd("test")
+	c.SetIn(strings.NewReader(in.String()))
+	c.SetOut(&out)
+	c.SetArgs([]string{"mcp", dir.Path()})
+	r.NoError(c.Execute())
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	r.Len(lines, len(expected))
+	for i, e := range expected {
+		r.JSONEq(e, lines[i])
+	}
+}
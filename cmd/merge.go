@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/spf13/cobra"
+)
+
+type mergeOptions struct {
+	vault  string
+	dryRun bool
+	scan   scanOptions
+}
+
+func newMergeCmd(version string) *cobra.Command {
+	var opts mergeOptions
+
+	cmd := &cobra.Command{
+		Use:   "merge <target> <source...>",
+		Short: "Merge several tags into one",
+		Args:  cobra.MinimumNArgs(2),
+		Example: `
+		# fold ml and ML into machine-learning
+		tobi merge machine-learning ml ML --vault /path/to/your/vault
+
+		# preview the changes without writing any file
+		tobi merge machine-learning ml ML --dry-run
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var tags []string
+			for _, a := range args {
+				t := strings.TrimPrefix(a, "#")
+				if !tagx.IsValid(t) {
+					return fmt.Errorf("invalid tag %q", t)
+				}
+				tags = append(tags, t)
+			}
+
+			target, sources := tags[0], tags[1:]
+			if slices.Contains(sources, target) {
+				return fmt.Errorf("tag %q would be merged into itself", target)
+			}
+
+			var vaultArgs []string
+			if opts.vault != "" {
+				vaultArgs = append(vaultArgs, opts.vault)
+			}
+			root, err := vaultFromArgs(vaultArgs)
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan)
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			edits, err := rewriteNotes(sc, notePaths(res, sources, false), func(s string) (string, int, error) {
+				return tagx.Merge(s, target, sources)
+			})
+			if err != nil {
+				return err
+			}
+
+			return edits.apply(cmd.Context(), cmd.OutOrStdout(), sc, opts.dryRun, "merged")
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&opts.vault, "vault", "V", "", "path to the vault. Defaults to OBSIDIAN_VAULT_PATH.")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "print a diff of the changes without writing any file")
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_mergeCmd(t *testing.T) {
+	files := map[string]string{
+		"a.md": "---\ntags: [machine-learning, ml, python]\n---\n#ML\n",
+		"b.md": "---\ntags:\n  - ml\n  - ML\n---\n",
+		"c.md": "#mlops #machine-learning\n",
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		want     map[string]string
+	}{
+		{
+			name:     "merge",
+			args:     []string{"machine-learning", "ml", "#ML"},
+			expected: "2  a.md\n2  b.md\nmerged 4 tags in 2 notes\n",
+			want: map[string]string{
+				"a.md": "---\ntags: [machine-learning, python]\n---\n#machine-learning\n",
+				"b.md": "---\ntags:\n  - machine-learning\n---\n",
+			},
+		},
+		{
+			name: "dry run",
+			args: []string{"ml", "ML", "--dry-run"},
+			expected: `--- a/a.md
++++ b/a.md
+@@ -1,4 +1,4 @@
+ ---
+ tags: [machine-learning, ml, python]
+ ---
+-#ML
++#ml
+--- a/b.md
++++ b/b.md
+@@ -1,5 +1,4 @@
+ ---
+ tags:
+   - ml
+-  - ML
+ ---
+`,
+		},
+		{
+			name:     "unknown tags",
+			args:     []string{"machine-learning", "ai"},
+			expected: "merged 0 tags in 0 notes\n",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			dir := fs.NewDir(t, "test", fs.WithFiles(files))
+			defer dir.Remove()
+
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"merge", "--vault", dir.Path()}, tt.args...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+
+			for name, content := range files {
+				want, ok := tt.want[name]
+				if !ok {
+					want = content
+				}
+				got, err := os.ReadFile(filepath.Join(dir.Path(), name))
+				r.NoError(err)
+				r.Equal(want, string(got), name)
+			}
+		})
+	}
+}
+
+func Test_mergeCmd_ErrorCases(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFile("a.md", "#ml\n"))
+	defer dir.Remove()
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing source", args: []string{"machine-learning"}},
+		{name: "invalid source", args: []string{"machine-learning", "machine learning"}},
+		{name: "target among sources", args: []string{"ml", "ML", "#ml"}},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			c := NewRootCmd("test")
+			c.SetOut(&strings.Builder{})
+			c.SetErr(&strings.Builder{})
+			c.SetArgs(append([]string{"merge", "--vault", dir.Path()}, tt.args...))
+
+			r.Error(c.Execute())
+		})
+	}
+	// notes cannot be rewritten as of a revision
+	c := NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{"merge", "--vault", dir.Path(), "machine-learning", "ml", "--rev", "HEAD", "--dry-run"})
+	r.ErrorContains(c.Execute(), "unknown flag: --rev")
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+type notesOptions struct {
+	descendants bool
+	limit       int
+	scan        scanOptions
+	format      outputFormat
+}
+
+func newNotesCmd(version string) *cobra.Command {
+	var opts notesOptions
+
+	cmd := &cobra.Command{
+		Use:   "notes <tag> [path]",
+		Short: "List notes carrying a tag",
+		Args:  cobra.RangeArgs(1, 2),
+		Example: `
+		# list notes tagged with project/alpha
+		tobi notes project/alpha /path/to/your/vault
+
+		# include notes tagged with any tag nested under project
+		tobi notes project --descendants
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tag := strings.TrimPrefix(args[0], "#")
+
+			root, err := vaultFromArgs(args[1:])
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan)
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			report := newNotesReport(res, tag, opts.descendants, opts.limit)
+			return report.render(cmd.OutOrStdout(), opts.format)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.BoolVarP(&opts.descendants, "descendants", "D", false, "include notes carrying tags nested under the tag")
+	flags.IntVarP(&opts.limit, "limit", "l", 0, "number of notes to display. Non-positive values mean all.")
+	addFormatFlag(cmd, &opts.format)
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// noteMatch is a note carrying a tag.
+type noteMatch struct {
+	// Path is the slash-separated, vault-relative path of the note.
+	Path string `json:"path" yaml:"path"`
+	// Count is the number of occurrences of the tag in the note.
+	Count int `json:"count" yaml:"count"`
+}
+
+// notesWithTag returns the notes carrying tag, and any tag nested under it if
+// descendants is true. Notes are sorted by descending number of occurrences,
+// then by path.
+func notesWithTag(res *vault.Result, tag string, descendants bool) []noteMatch {
+	var matches []noteMatch
+	for _, note := range res.Notes {
+		n := 0
+		for _, t := range note.Tags {
+			if t == tag || (descendants && tagx.IsDescendant(t, tag)) {
+				n++
+			}
+		}
+		if n > 0 {
+			matches = append(matches, noteMatch{Path: note.Path, Count: n})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b noteMatch) int {
+		if c := b.Count - a.Count; c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return matches
+}
+
+// newNotesReport returns the notes of res carrying tag, or with descendants,
+// any tag nested under it, truncated to limit. Non-positive limits mean all
+// notes. The total counts all matching notes.
+func newNotesReport(res *vault.Result, tag string, descendants bool, limit int) notesReport {
+	report := notesReport{
+		Vault:       res.Root,
+		Notes:       len(res.Notes),
+		CacheHit:    res.CacheHit,
+		Tag:         tag,
+		Descendants: descendants,
+		Matches:     notesWithTag(res, tag, descendants),
+	}
+	if report.Matches == nil {
+		report.Matches = []noteMatch{}
+	}
+	for _, m := range report.Matches {
+		report.Total += m.Count
+	}
+	if limit > 0 {
+		report.Matches = report.Matches[:min(len(report.Matches), limit)]
+	}
+	return report
+}
+
+// notesReport is the document written by the json and yaml formats of the
+// notes command.
+type notesReport struct {
+	Vault string `json:"vault" yaml:"vault"`
+	Notes int    `json:"notes" yaml:"notes"`
+	// Total is the number of occurrences of the tag across all matching notes.
+	Total       int         `json:"total" yaml:"total"`
+	CacheHit    bool        `json:"cacheHit" yaml:"cacheHit"`
+	Tag         string      `json:"tag" yaml:"tag"`
+	Descendants bool        `json:"descendants" yaml:"descendants"`
+	Matches     []noteMatch `json:"matches" yaml:"matches"`
+}
+
+type noteRecord struct {
+	Type string `json:"type"`
+	noteMatch
+}
+
+func (r notesReport) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, m := range r.Matches {
+			fmt.Fprintf(tw, "%d\t%s\n", m.Count, m.Path)
+		}
+		return tw.Flush()
+	case jsonFormat, yamlFormat:
+		if r.Matches == nil {
+			r.Matches = []noteMatch{}
+		}
+		return writeDocument(w, format, r)
+	case ndjsonFormat:
+		records := []any{scanRecord{
+			Type:     "scan",
+			Vault:    r.Vault,
+			Notes:    r.Notes,
+			Total:    r.Total,
+			CacheHit: r.CacheHit,
+		}}
+		for _, m := range r.Matches {
+			records = append(records, noteRecord{Type: "note", noteMatch: m})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		rows := make([][]string, 0, len(r.Matches))
+		for _, m := range r.Matches {
+			rows = append(rows, []string{m.Path, strconv.Itoa(m.Count)})
+		}
+		return writeTable(w, format, []string{"path", "count"}, rows)
+	}
+
+	return nil
+}
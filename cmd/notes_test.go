@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_notesWithTag(t *testing.T) {
+	res := &vault.Result{
+		Notes: []vault.Note{
+			{Path: "a.md", Tags: []string{"project/alpha", "golang", "project/alpha"}},
+			{Path: "b.md", Tags: []string{"project", "project/beta"}},
+			{Path: "d.md", Tags: []string{"projects"}},
+			{Path: "sub/c.md", Tags: []string{"project/alpha/docs"}},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		tag         string
+		descendants bool
+		want        []noteMatch
+	}{
+		{
+			name: "exact tag",
+			tag:  "project/alpha",
+			want: []noteMatch{
+				{Path: "a.md", Count: 2},
+			},
+		},
+		{
+			name:        "with descendants",
+			tag:         "project/alpha",
+			descendants: true,
+			want: []noteMatch{
+				{Path: "a.md", Count: 2},
+				{Path: "sub/c.md", Count: 1},
+			},
+		},
+		{
+			name:        "ancestor with descendants",
+			tag:         "project",
+			descendants: true,
+			want: []noteMatch{
+				{Path: "a.md", Count: 2},
+				{Path: "b.md", Count: 2},
+				{Path: "sub/c.md", Count: 1},
+			},
+		},
+		{
+			name: "unknown tag",
+			tag:  "rust",
+			want: nil,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			r.Equal(tt.want, notesWithTag(res, tt.tag, tt.descendants))
+		})
+	}
+}
+
+func Test_notesCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"a.md": "---\ntags: [golang]\n---\n#golang/cobra and #golang",
+			"b.md": "#golang/cobra",
+			"c.md": "#rust",
+		}),
+	)
+	defer dir.Remove()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "text",
+			args:     []string{"#golang", dir.Path()},
+			expected: "2  a.md\n",
+		},
+		{
+			name:     "descendants",
+			args:     []string{"golang", dir.Path(), "--descendants"},
+			expected: "3  a.md\n1  b.md\n",
+		},
+		{
+			name:     "csv",
+			args:     []string{"golang/cobra", dir.Path(), "--format", "csv"},
+			expected: "path,count\na.md,1\nb.md,1\n",
+		},
+		{
+			name: "json",
+			args: []string{"rust", dir.Path(), "--format", "json"},
+			expected: `{
+  "vault": "` + dir.Path() + `",
+  "notes": 3,
+  "total": 1,
+  "cacheHit": true,
+  "tag": "rust",
+  "descendants": false,
+  "matches": [
+    {
+      "path": "c.md",
+      "count": 1
+    }
+  ]
+}
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	// warm up the cache so that every case is served from it
+	warmup := NewRootCmd("test")
+	warmup.SetOut(&strings.Builder{})
+	warmup.SetArgs([]string{dir.Path()})
+	r.NoError(warmup.Execute())
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"notes"}, tt.args...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
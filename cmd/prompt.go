@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+// defaultPromptTemplate lists the tag hierarchy one tag per line, indented
+// under its parent, which costs far fewer tokens than full tag paths.
+const defaultPromptTemplate = `{{- define "tag" -}}
+{{ indent .Depth }}{{ .Name }} ({{ .Total }})
+{{- with .Examples }}: {{ join . "; " }}{{ end }}
+{{ range .Children }}{{ template "tag" . }}{{ end }}
+{{- end -}}
+Existing tags of a vault with {{ .Notes }} notes, one per line as "name (uses)".
+Nested tags are indented under their parent and written in full as parent/name. Uses include nested tags.
+{{- if .Examples }} Tags are followed by the titles of some notes carrying them.{{ end }}
+
+{{ range .Tags }}{{ template "tag" . }}{{ end -}}
+{{ with .Omitted }}({{ . }} less used tags omitted)
+{{ end -}}
+`
+
+// promptFuncs are the functions available to prompt templates.
+var promptFuncs = template.FuncMap{
+	"indent": func(depth int) string { return strings.Repeat("  ", depth) },
+	"join":   strings.Join,
+}
+
+type promptOptions struct {
+	template  string
+	examples  int
+	maxTokens int
+	scan      scanOptions
+}
+
+func newPromptCmd(version string) *cobra.Command {
+	var opts promptOptions
+
+	cmd := &cobra.Command{
+		Use:   "prompt [path]",
+		Short: "Describe the tags of a vault for an LLM prompt",
+		Args:  cobra.RangeArgs(0, 1),
+		Example: `
+		# ask for tags that fit the existing hierarchy
+		tobi prompt /path/to/your/vault | llm "Suggest tags for this note: $(cat note.md)"
+
+		# include 2 example note titles per tag, in about 2000 tokens
+		tobi prompt --examples 2 --max-tokens 2000
+
+		# use your own template
+		tobi prompt --template tags.tmpl
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tmpl, err := parsePromptTemplate(opts.template)
+			if err != nil {
+				return err
+			}
+
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			p := newPrompt(res, opts.examples)
+			return p.render(cmd.OutOrStdout(), tmpl, opts.maxTokens)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&opts.template, "template", "T", "", "text/template file to render instead of the built-in template")
+	flags.IntVarP(&opts.examples, "examples", "e", 0, "number of example note titles to list per tag")
+	flags.IntVar(&opts.maxTokens, "max-tokens", 0, "approximate token budget, met by leaving out the least used tags. Non-positive values mean no limit.")
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// parsePromptTemplate parses the template in file, or the built-in template if
+// file is empty.
+func parsePromptTemplate(file string) (*template.Template, error) {
+	text := defaultPromptTemplate
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+
+	return template.New("prompt").Funcs(promptFuncs).Parse(text)
+}
+
+// promptTag is a tag in the data of prompt templates.
+type promptTag struct {
+	// Tag is the full tag, e.g. "golang/cobra".
+	Tag string
+	// Name is the last segment of the tag, e.g. "cobra".
+	Name string
+	// Depth is the nesting level of the tag, 0 for top-level tags.
+	Depth int
+	// Count is the number of usages of exactly this tag.
+	Count int
+	// Total is the number of usages of this tag and all of its descendants.
+	Total int
+	// Examples are the titles of the first notes carrying exactly this tag.
+	Examples []string
+	// Children are sorted by descending total, then by name.
+	Children []*promptTag
+}
+
+// promptData is the data of prompt templates.
+type promptData struct {
+	Vault string
+	Notes int
+	Total int
+	// Tags are the top-level tags, sorted by descending total, then by name.
+	Tags []*promptTag
+	// Examples is true if example note titles were requested.
+	Examples bool
+	// Omitted is the number of tags left out to meet the token budget.
+	Omitted int
+}
+
+// prompt holds the tag hierarchy of a vault and the example titles of every
+// tag, from which the data of prompt templates is built.
+type prompt struct {
+	res      *vault.Result
+	tree     *tagx.TagNode
+	examples map[string][]string
+	// wantExamples is true if example note titles were requested.
+	wantExamples bool
+}
+
+// newPrompt returns the prompt of res, with up to examples note titles per
+// tag. Titles are note file names without extension, as shown by Obsidian,
+// and are taken from the first notes by path.
+func newPrompt(res *vault.Result, examples int) *prompt {
+	p := &prompt{
+		res:          res,
+		tree:         tagx.NewTagTree(res.Counts),
+		examples:     make(map[string][]string),
+		wantExamples: examples > 0,
+	}
+
+	if examples <= 0 {
+		return p
+	}
+
+	notes := slices.Clone(res.Notes)
+	slices.SortFunc(notes, func(a, b vault.Note) int { return cmp.Compare(a.Path, b.Path) })
+	for _, n := range notes {
+		title := strings.TrimSuffix(path.Base(n.Path), path.Ext(n.Path))
+		for _, t := range n.Tags {
+			ex := p.examples[t]
+			if len(ex) < examples && !slices.Contains(ex, title) {
+				p.examples[t] = append(ex, title)
+			}
+		}
+	}
+
+	return p
+}
+
+// data returns the data of prompt templates, without the tags in omit.
+func (p *prompt) data(omit map[string]bool) promptData {
+	d := promptData{
+		Vault:    p.res.Root,
+		Notes:    len(p.res.Notes),
+		Total:    p.res.Total,
+		Examples: p.wantExamples,
+		Omitted:  len(omit),
+	}
+
+	var convert func(nodes []*tagx.TagNode, depth int) []*promptTag
+	convert = func(nodes []*tagx.TagNode, depth int) []*promptTag {
+		var tags []*promptTag
+		for _, n := range nodes {
+			if omit[n.Tag] {
+				continue
+			}
+			tags = append(tags, &promptTag{
+				Tag:      n.Tag,
+				Name:     n.Name,
+				Depth:    depth,
+				Count:    n.Count,
+				Total:    n.Total,
+				Examples: p.examples[n.Tag],
+				Children: convert(n.Children, depth+1),
+			})
+		}
+		return tags
+	}
+	d.Tags = convert(p.tree.Children, 0)
+
+	return d
+}
+
+// omitOrder returns every tag in the order they are left out to meet a token
+// budget: least used first, and deepest first among equally used tags. Since
+// a tag is used at least as often as any of its descendants, a tag always
+// comes after its descendants, so leaving out any prefix of the order prunes
+// whole branches.
+func (p *prompt) omitOrder() []string {
+	type entry struct {
+		tag   string
+		total int
+		depth int
+	}
+
+	var entries []entry
+	var walk func(n *tagx.TagNode, depth int)
+	walk = func(n *tagx.TagNode, depth int) {
+		for _, c := range n.Children {
+			entries = append(entries, entry{tag: c.Tag, total: c.Total, depth: depth})
+			walk(c, depth+1)
+		}
+	}
+	walk(p.tree, 0)
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Or(
+			cmp.Compare(a.total, b.total),
+			cmp.Compare(b.depth, a.depth),
+			cmp.Compare(b.tag, a.tag),
+		)
+	})
+
+	order := make([]string, len(entries))
+	for i, e := range entries {
+		order[i] = e.tag
+	}
+	return order
+}
+
+// render executes tmpl with the prompt data and writes the result to w. If
+// maxTokens is positive, the least used tags are left out until the result
+// fits in about maxTokens tokens.
+func (p *prompt) render(w io.Writer, tmpl *template.Template, maxTokens int) error {
+	execute := func(omit map[string]bool) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, p.data(omit)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	out, err := execute(nil)
+	if err != nil {
+		return err
+	}
+
+	if maxTokens > 0 && approxTokens(out) > maxTokens {
+		order := p.omitOrder()
+
+		// the output shrinks as more tags are left out, but for the note
+		// about omitted tags, so search for about the fewest tags to leave out
+		lo, hi := 1, len(order)
+		out = nil
+		for lo <= hi {
+			mid := lo + (hi-lo)/2
+			b, err := execute(setOf(order[:mid]))
+			if err != nil {
+				return err
+			}
+			if approxTokens(b) <= maxTokens {
+				out, hi = b, mid-1
+			} else {
+				lo = mid + 1
+			}
+		}
+
+		if out == nil {
+			return fmt.Errorf("prompt does not fit in %d tokens, even without tags", maxTokens)
+		}
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// approxTokens estimates the number of tokens of b for common LLM tokenizers,
+// at about 4 characters per token.
+func approxTokens(b []byte) int {
+	return (utf8.RuneCount(b) + 3) / 4
+}
+
+func setOf(tags []string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		set[t] = true
+	}
+	return set
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_promptCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("Intro to Go.md", "#golang #golang/cobra"),
+		fs.WithFile("b.md", "#golang/cobra #cli"),
+		fs.WithFile("c.md", "#golang/cobra/Command #cli #daily"),
+		fs.WithFile("d.md", "#rust"),
+		fs.WithFile(".tobi.exclude", "daily"),
+		fs.WithFile("tags.tmpl", `{{ range .Tags }}{{ .Tag }}={{ .Count }}/{{ .Total }} {{ end }}`),
+	)
+	defer dir.Remove()
+
+	header := `Existing tags of a vault with 4 notes, one per line as "name (uses)".
+Nested tags are indented under their parent and written in full as parent/name. Uses include nested tags.`
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "default",
+			args: []string{},
+			expected: header + `
+
+golang (4)
+  cobra (3)
+    Command (1)
+cli (2)
+rust (1)
+`,
+		},
+		{
+			name: "examples",
+			args: []string{"--examples", "2"},
+			expected: header + ` Tags are followed by the titles of some notes carrying them.
+
+golang (4): Intro to Go
+  cobra (3): Intro to Go; b
+    Command (1): c
+cli (2): b; c
+rust (1): d
+`,
+		},
+		{
+			name: "max tokens",
+			args: []string{"--max-tokens", "58"},
+			expected: header + `
+
+golang (4)
+  cobra (3)
+(3 less used tags omitted)
+`,
+		},
+		{
+			name:     "template",
+			args:     []string{"--template", dir.Join("tags.tmpl")},
+			expected: "golang=1/4 cli=2/2 rust=1/1 ",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"prompt", dir.Path()}, tt.args...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func Test_promptCmd_ErrorCases(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("a.md", "#golang"),
+		fs.WithFile("bad.tmpl", "{{ .Tags "),
+	)
+	defer dir.Remove()
+
+	testCases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "invalid template",
+			args: []string{"--template", dir.Join("bad.tmpl")},
+			err:  "template: prompt:1: unclosed action",
+		},
+		{
+			name: "missing template",
+			args: []string{"--template", dir.Join("missing.tmpl")},
+			err:  "no such file or directory",
+		},
+		{
+			name: "budget too small",
+			args: []string{"--max-tokens", "5"},
+			err:  "prompt does not fit in 5 tokens, even without tags",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			c := NewRootCmd("test")
+			c.SetOut(&strings.Builder{})
+			c.SetErr(&strings.Builder{})
+			c.SetArgs(append([]string{"prompt", dir.Path()}, tt.args...))
+
+			r.ErrorContains(c.Execute(), tt.err)
+		})
+	}
+}
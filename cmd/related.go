@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+type pairSort enumflag.Flag
+
+const (
+	byJaccard pairSort = iota
+	byNotes
+	byPMI
+)
+
+var pairSortIDs = map[pairSort][]string{
+	byJaccard: {"jaccard"},
+	byNotes:   {"notes"},
+	byPMI:     {"pmi"},
+}
+
+func pairSortUsage() string {
+	v := slices.Collect(enumVariants(pairSortIDs))
+	return fmt.Sprintf("sort related tags by (%s)", strings.Join(v, "|"))
+}
+
+// addPairFlags adds the --sort and --min-notes flags to cmd.
+func addPairFlags(cmd *cobra.Command, sort *pairSort, minNotes *int) {
+	flags := cmd.Flags()
+	flags.VarP(
+		enumflag.New(sort, "sort", pairSortIDs, enumflag.EnumCaseSensitive),
+		"sort", "s", pairSortUsage(),
+	)
+	flags.IntVar(minNotes, "min-notes", 1, "only pairs of tags carried together by at least this many notes")
+}
+
+// sortPairs sorts pairs by the key selected by by, in descending order, then
+// by number of notes and by tags.
+func sortPairs(pairs []vault.Pair, by pairSort) {
+	slices.SortStableFunc(pairs, func(x, y vault.Pair) int {
+		var c int
+		switch by {
+		case byJaccard:
+			c = cmp.Compare(y.Jaccard, x.Jaccard)
+		case byPMI:
+			c = cmp.Compare(y.PMI, x.PMI)
+		}
+		return cmp.Or(c, cmp.Compare(y.Notes, x.Notes), cmp.Compare(x.A, y.A), cmp.Compare(x.B, y.B))
+	})
+}
+
+type relatedOptions struct {
+	limit    int
+	sort     pairSort
+	minNotes int
+	format   outputFormat
+	scan     scanOptions
+}
+
+func newRelatedCmd(version string) *cobra.Command {
+	var opts relatedOptions
+
+	cmd := &cobra.Command{
+		Use:   "related <tag> [path]",
+		Short: "List tags used together with a tag",
+		Args:  cobra.RangeArgs(1, 2),
+		Example: `
+		# tags most often used together with golang
+		tobi related golang /path/to/your/vault
+
+		# rank by pointwise mutual information, ignoring one-off pairs
+		tobi related golang --sort pmi --min-notes 2
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tag := strings.TrimPrefix(args[0], "#")
+			if !tagx.IsValid(tag) {
+				return fmt.Errorf("invalid tag %q", tag)
+			}
+
+			root, err := vaultFromArgs(args[1:])
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			co := vault.NewCooccurrence(res.Notes)
+			pairs := co.Related(tag, opts.minNotes)
+			sortPairs(pairs, opts.sort)
+			if opts.limit > 0 {
+				pairs = pairs[:min(len(pairs), opts.limit)]
+			}
+
+			report := relatedReport{
+				Vault:   res.Root,
+				Notes:   len(res.Notes),
+				Tag:     tag,
+				Tagged:  co.Notes(tag),
+				Related: make([]relatedTag, 0, len(pairs)),
+			}
+			for _, p := range pairs {
+				report.Related = append(report.Related, relatedTag{
+					Tag:     p.B,
+					Notes:   p.Notes,
+					Jaccard: p.Jaccard,
+					PMI:     p.PMI,
+				})
+			}
+
+			return report.render(cmd.OutOrStdout(), opts.format)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.IntVarP(&opts.limit, "limit", "l", 8, "number of related tags to display. Non-positive values mean all.")
+	addPairFlags(cmd, &opts.sort, &opts.minNotes)
+	addFormatFlag(cmd, &opts.format)
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// relatedTag is a tag used together with the queried tag.
+type relatedTag struct {
+	Tag string `json:"tag" yaml:"tag"`
+	// Notes is the number of notes carrying both tags.
+	Notes   int     `json:"notes" yaml:"notes"`
+	Jaccard float64 `json:"jaccard" yaml:"jaccard"`
+	PMI     float64 `json:"pmi" yaml:"pmi"`
+}
+
+// relatedReport is the document written by the json and yaml formats.
+type relatedReport struct {
+	Vault string `json:"vault" yaml:"vault"`
+	Notes int    `json:"notes" yaml:"notes"`
+	Tag   string `json:"tag" yaml:"tag"`
+	// Tagged is the number of notes carrying the tag.
+	Tagged  int          `json:"tagged" yaml:"tagged"`
+	Related []relatedTag `json:"related" yaml:"related"`
+}
+
+type relatedRecord struct {
+	Type string `json:"type"`
+	relatedTag
+}
+
+func (r relatedReport) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, t := range r.Related {
+			fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%s\n", t.Notes, t.Jaccard, t.PMI, t.Tag)
+		}
+		return tw.Flush()
+	case jsonFormat, yamlFormat:
+		return writeDocument(w, format, r)
+	case ndjsonFormat:
+		records := make([]any, 0, len(r.Related))
+		for _, t := range r.Related {
+			records = append(records, relatedRecord{Type: "related", relatedTag: t})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		rows := make([][]string, 0, len(r.Related))
+		for _, t := range r.Related {
+			rows = append(rows, []string{
+				t.Tag,
+				strconv.Itoa(t.Notes),
+				strconv.FormatFloat(t.Jaccard, 'f', -1, 64),
+				strconv.FormatFloat(t.PMI, 'f', -1, 64),
+			})
+		}
+		return writeTable(w, format, []string{"tag", "notes", "jaccard", "pmi"}, rows)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+var cooccurrenceFiles = map[string]string{
+	"a.md":          "#golang #cobra",
+	"b.md":          "#golang #cobra #cli",
+	"c.md":          "#golang #daily",
+	"d.md":          "#rust #cli",
+	"e.md":          "#daily",
+	".tobi.exclude": "daily",
+}
+
+func Test_relatedCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFiles(cooccurrenceFiles))
+	defer dir.Remove()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "by jaccard",
+			args:     []string{"golang"},
+			expected: "2  0.67  0.74   cobra\n1  0.25  -0.26  cli\n",
+		},
+		{
+			name:     "by notes with min notes",
+			args:     []string{"#golang", "--sort", "notes", "--min-notes", "2"},
+			expected: "2  0.67  0.74  cobra\n",
+		},
+		{
+			name:     "by pmi",
+			args:     []string{"cli", "--sort", "pmi"},
+			expected: "1  0.50  1.32   rust\n1  0.33  0.32   cobra\n1  0.25  -0.26  golang\n",
+		},
+		{
+			name:     "limit",
+			args:     []string{"cli", "--limit", "1"},
+			expected: "1  0.50  1.32  rust\n",
+		},
+		{
+			name:     "excluded tag",
+			args:     []string{"daily"},
+			expected: "",
+		},
+		{
+			name: "json",
+			args: []string{"rust", "--format", "json"},
+			expected: `{
+  "vault": "` + dir.Path() + `",
+  "notes": 5,
+  "tag": "rust",
+  "tagged": 1,
+  "related": [
+    {
+      "tag": "cli",
+      "notes": 1,
+      "jaccard": 0.5,
+      "pmi": 1.3219280948873622
+    }
+  ]
+}
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"related", tt.args[0], dir.Path()}, tt.args[1:]...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
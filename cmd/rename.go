@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+type renameOptions struct {
+	descendants bool
+	dryRun      bool
+	scan        scanOptions
+}
+
+func newRenameCmd(version string) *cobra.Command {
+	var opts renameOptions
+
+	cmd := &cobra.Command{
+		Use:   "rename <old> <new> [path]",
+		Short: "Rename a tag across the vault",
+		Args:  cobra.RangeArgs(2, 3),
+		Example: `
+		# rename golang to go in every note of a vault
+		tobi rename golang go /path/to/your/vault
+
+		# move proj and every tag nested under it to project
+		tobi rename proj project --descendants
+
+		# preview the changes without writing any file
+		tobi rename proj project --descendants --dry-run
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from := strings.TrimPrefix(args[0], "#")
+			to := strings.TrimPrefix(args[1], "#")
+			for _, t := range []string{from, to} {
+				if !tagx.IsValid(t) {
+					return fmt.Errorf("invalid tag %q", t)
+				}
+			}
+			if from == to {
+				return fmt.Errorf("tag %q would be renamed to itself", from)
+			}
+
+			root, err := vaultFromArgs(args[2:])
+			if err != nil {
+				return err
+			}
+
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan)
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			rename := tagx.Renamer(from, to, opts.descendants)
+			edits, err := rewriteNotes(sc, notePaths(res, []string{from}, opts.descendants), func(s string) (string, int, error) {
+				return tagx.Rewrite(s, rename)
+			})
+			if err != nil {
+				return err
+			}
+
+			return edits.apply(cmd.Context(), cmd.OutOrStdout(), sc, opts.dryRun, "renamed")
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.BoolVarP(&opts.descendants, "descendants", "D", false, "also rename tags nested under the tag")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "print a diff of the changes without writing any file")
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// noteEdit is the rewritten content of a note.
+type noteEdit struct {
+	// path is the slash-separated, vault-relative path of the note.
+	path   string
+	before string
+	after  string
+	// count is the number of rewritten tag occurrences.
+	count int
+}
+
+type noteEdits []noteEdit
+
+// notePaths returns the sorted paths of the notes carrying any of tags, or any
+// tag nested under them if descendants is true, according to a scan result.
+func notePaths(res *vault.Result, tags []string, descendants bool) []string {
+	var paths []string
+	for _, t := range tags {
+		for _, m := range notesWithTag(res, t, descendants) {
+			paths = append(paths, m.Path)
+		}
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// rewriteNotes rewrites the notes at the given paths with rewrite, which
+// returns the new content of a note and the number of rewritten tags. Notes
+// without any rewritten tag are skipped. Nothing is written to disk.
+//
+// Returns an error if any note cannot be read or safely rewritten, so that a
+// change is either applied to every note or to none.
+func rewriteNotes(s *vault.Scanner, paths []string, rewrite func(string) (string, int, error)) (noteEdits, error) {
+	var edits noteEdits
+	for _, p := range paths {
+		before, err := s.ReadNote(p)
+		if err != nil {
+			return nil, err
+		}
+
+		after, n, err := rewrite(before)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if n == 0 {
+			continue
+		}
+
+		edits = append(edits, noteEdit{path: p, before: before, after: after, count: n})
+	}
+
+	return edits, nil
+}
+
+// apply writes the edits to the notes of the vault scanned by s and reports the
+// number of rewritten tags per note, using verb in the summary. The cache is
+// then updated with the rewritten notes, without rescanning the vault. If dryRun
+// is true, a diff of the edits is written to w instead.
+//
+// Nothing is written if ctx is done. Once writing has started, every edit is
+// written, so that notes are not left half renamed.
+func (es noteEdits) apply(ctx context.Context, w io.Writer, s *vault.Scanner, dryRun bool, verb string) error {
+	if dryRun {
+		return es.fPrintDiff(w)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range es {
+		if err := s.WriteNote(e.path, e.after); err != nil {
+			return err
+		}
+	}
+
+	notes := make(map[string]string, len(es))
+	for _, e := range es {
+		notes[e.path] = e.after
+	}
+	if err := s.Update(notes); err != nil {
+		log.Printf("failed to update cache of %s: %v", s.Root(), err)
+	}
+
+	es.fPrint(w, verb)
+	return nil
+}
+
+// fPrint writes the number of rewritten tags per note, followed by a summary.
+func (es noteEdits) fPrint(w io.Writer, verb string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	total := 0
+	for _, e := range es {
+		fmt.Fprintf(tw, "%d\t%s\n", e.count, e.path)
+		total += e.count
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "%s %d tags in %d notes\n", verb, total, len(es))
+}
+
+// fPrintDiff writes a unified diff of every edit.
+func (es noteEdits) fPrintDiff(w io.Writer) error {
+	for _, e := range es {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(e.before),
+			B:        diffLines(e.after),
+			FromFile: "a/" + e.path,
+			ToFile:   "b/" + e.path,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffLines splits s into lines, keeping line endings. A missing newline at the
+// end of s is added so that the last line prints on its own in a diff.
+func diffLines(s string) []string {
+	lines := slices.Collect(strings.Lines(s))
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+	return lines
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_renameCmd(t *testing.T) {
+	files := map[string]string{
+		"a.md":       "---\ntags:\n  - proj/alpha # main\n---\n#proj and `#proj`\n",
+		"b.md":       "#proj/beta #rust\n",
+		"c.md":       "#rust\n",
+		"ignored.md": "#proj\n",
+		".gitignore": "ignored.md\n",
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		want     map[string]string
+	}{
+		{
+			name:     "exact tag",
+			args:     []string{"proj", "project"},
+			expected: "1  a.md\nrenamed 1 tags in 1 notes\n",
+			want: map[string]string{
+				"a.md": "---\ntags:\n  - proj/alpha # main\n---\n#project and `#proj`\n",
+			},
+		},
+		{
+			name:     "with descendants",
+			args:     []string{"#proj", "#project", "--descendants"},
+			expected: "2  a.md\n1  b.md\nrenamed 3 tags in 2 notes\n",
+			want: map[string]string{
+				"a.md": "---\ntags:\n  - project/alpha # main\n---\n#project and `#proj`\n",
+				"b.md": "#project/beta #rust\n",
+			},
+		},
+		{
+			name: "dry run",
+			args: []string{"rust", "lang/rust", "--dry-run"},
+			expected: `--- a/b.md
++++ b/b.md
+@@ -1 +1 @@
+-#proj/beta #rust
++#proj/beta #lang/rust
+--- a/c.md
++++ b/c.md
+@@ -1 +1 @@
+-#rust
++#lang/rust
+`,
+		},
+		{
+			name:     "unknown tag",
+			args:     []string{"golang", "go"},
+			expected: "renamed 0 tags in 0 notes\n",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			dir := fs.NewDir(t, "test", fs.WithFiles(files))
+			defer dir.Remove()
+
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"rename", tt.args[0], tt.args[1], dir.Path()}, tt.args[2:]...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+
+			for name, content := range files {
+				want, ok := tt.want[name]
+				if !ok {
+					want = content
+				}
+				got, err := os.ReadFile(filepath.Join(dir.Path(), name))
+				r.NoError(err)
+				r.Equal(want, string(got), name)
+			}
+
+			// the cache is up to date with the rewritten notes
+			_, res, err := scanVault(context.Background(), dir.Path(), "test", scanOptions{})
+			r.NoError(err)
+			r.True(res.CacheHit)
+		})
+	}
+}
+
+func Test_renameCmd_ErrorCases(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"a.md": "---\ntags:\n  - golang\n---\n",
+			"b.md": "#golang\n",
+		}),
+	)
+	defer dir.Remove()
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "invalid new tag", args: []string{"golang", "go lang"}},
+		{name: "numeric old tag", args: []string{"1984", "y1984"}},
+		{name: "same tag", args: []string{"golang", "#golang"}},
+		{name: "unsafe rewrite", args: []string{"golang", "null"}},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			c := NewRootCmd("test")
+			c.SetOut(&strings.Builder{})
+			c.SetErr(&strings.Builder{})
+			c.SetArgs(append([]string{"rename"}, append(tt.args, dir.Path())...))
+
+			r.Error(c.Execute())
+
+			// nothing is written when any note cannot be rewritten
+			got, err := os.ReadFile(filepath.Join(dir.Path(), "b.md"))
+			r.NoError(err)
+			r.Equal("#golang\n", string(got))
+		})
+	}
+	// notes cannot be rewritten as of a revision
+	c := NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{"rename", "golang", "go", dir.Path(), "--rev", "HEAD", "--dry-run"})
+	r.ErrorContains(c.Execute(), "unknown flag: --rev")
+}
@@ -1,34 +1,30 @@
 package cmd
 
 import (
-	"encoding/binary"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"hash/fnv"
 	"io"
-	"io/fs"
-	"log"
 	"maps"
 	"os"
-	"path/filepath"
 	"slices"
+	"strings"
 	"text/tabwriter"
 
-	set "github.com/deckarep/golang-set/v2"
-	"github.com/nt54hamnghi/tobi/pkg/gitignore"
-	"github.com/nt54hamnghi/tobi/pkg/tagx"
-	"github.com/sourcegraph/conc/pool"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
 	"github.com/spf13/cobra"
 	"github.com/thediveo/enumflag/v2"
 )
 
 type rootOptions struct {
 	limit       int
-	noCache     bool
+	scan        scanOptions
+	depth       int
 	displayMode displayMode
+	format      outputFormat
 }
 
-func NewRootCmd() *cobra.Command {
+func NewRootCmd(version string) *cobra.Command {
 	var opts rootOptions
 
 	cmd := &cobra.Command{
@@ -39,8 +35,20 @@ func NewRootCmd() *cobra.Command {
 		# list all tags in a vault directory
 		tobi /path/to/your/vault
 
+		# list all tags in a zip export or tarball backup of a vault
+		tobi /path/to/your/vault.zip
+
+		# list all tags as of ten commits ago
+		tobi . --rev HEAD~10
+
 		# list the top 5 most used tags (with counts)
 		tobi . --limit 5 --mode count
+
+		# show the tag hierarchy, two levels deep
+		tobi . --mode tree --depth 2
+
+		# print all tags as JSON
+		tobi . --limit 0 --format json
 		`,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			// nothing has been provided, offer subcommands AND fall back to files
@@ -50,59 +58,20 @@ func NewRootCmd() *cobra.Command {
 
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				p, exist := os.LookupEnv("OBSIDIAN_VAULT_PATH")
-				if !exist {
-					return fmt.Errorf("path not provided and OBSIDIAN_VAULT_PATH is not set")
-				}
-				args = append(args, p)
-			}
-
-			p, err := filepath.Abs(args[0])
-			if err != nil {
-				return err
-			}
-
-			root, err := newVaultPath(p)
-			if err != nil {
-				return err
-			}
-
-			isIgnored, err := tagx.NewTagGlobs(root.excludePath())
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := vaultFromArgs(args)
 			if err != nil {
 				return err
 			}
 
-			ns, err := listNotes(root)
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
 			if err != nil {
 				return err
 			}
+			defer sc.Close()
 
-			var tc tagCounts
-
-			if !opts.noCache {
-				// try to read cache
-				tc, err = newTagCountsFromCache(root)
-				// if cache is valid and no changes was detected, return it
-				if err == nil && tc.Hash == ns.hash {
-					tc.print(opts)
-					return nil
-				}
-			}
-
-			// cache is disabled or cache file is stale, corrupted, or missing
-			// compute tag counts
-			tc = collectTags(ns, isIgnored.Match)
-
-			// write computed tag counts to cache
-			if err := tc.writeCache(root); err != nil {
-				// failing to write cache is not a fatal error, just log it
-				log.Printf("failed to write cache to %s: %v", root.cachePath(), err)
-			}
-
-			tc.print(opts)
-			return nil
+			tc := tagCounts{Tags: res.Counts, Total: res.Total}
+			return tc.render(cmd.OutOrStdout(), newScanInfo(res), opts)
 		},
 	}
 
@@ -113,135 +82,203 @@ func NewRootCmd() *cobra.Command {
 		enumflag.New(&opts.displayMode, "mode", displayModeIDs, enumflag.EnumCaseSensitive),
 		"mode", "m", displayModeUsage(),
 	)
-	flags.BoolVarP(&opts.noCache, "no-cache", "n", false, "disable cache")
+	flags.IntVarP(&opts.depth, "depth", "d", 0, "maximum depth of the tree in tree mode. Non-positive values mean unlimited.")
+	addFormatFlag(cmd, &opts.format)
+	addRevFlag(cmd, &opts.scan)
+	addScanFlags(cmd, &opts.scan)
 
 	// set up completion for display mode flag
 	if err := cmd.RegisterFlagCompletionFunc("mode", completeDisplayModeFlag); err != nil {
 		os.Exit(1)
 	}
 
+	cmd.AddCommand(
+		newNotesCmd(version),
+		newRenameCmd(version),
+		newMergeCmd(version),
+		newDiffCmd(version),
+		newHistoryCmd(version),
+		newRelatedCmd(version),
+		newExportCmd(version),
+		newPromptCmd(version),
+		newSuggestCmd(version),
+		newLintCmd(version),
+		newWatchCmd(version),
+		newServeCmd(version),
+		newMCPCmd(version),
+	)
+
 	return cmd
 }
 
-func subcommands(cmd *cobra.Command) []string {
-	var subs []string
-	for _, c := range cmd.Commands() {
-		if !c.Hidden {
-			subs = append(subs, c.Name())
-		}
-	}
-	return subs
+// Exit statuses of the root command.
+const (
+	// exitFailure is the exit status of commands that fail.
+	exitFailure = 1
+	// exitError is the exit status of commands that fail, among those that
+	// report their outcome with exitFailure; see reportsStatus.
+	exitError = 2
+)
+
+// statusError is an error that exits with a given status, such as the
+// outcome of diff --exit-code, or the failure of such a command.
+type statusError struct {
+	err  error
+	code int
 }
 
-type tagCounts struct {
-	Tags  map[string]int `json:"tags"`
-	Hash  uint64         `json:"hash"`
-	Total int            `json:"total"`
+func (e *statusError) Error() string {
+	return e.err.Error()
 }
 
-// collectTags processes all note files concurrently and extracts tags from their
-// YAML frontmatter, filtering out tags using the provided ignoreFunc predicate.
-// Returns a tagCounts struct with the frequency map, vault hash, and total number of tags.
-//
-// Files that cannot be processed due to errors are logged and skipped.
-func collectTags(ns noteSet, ignoreFunc func(string) bool) tagCounts {
-	tc := tagCounts{
-		Hash: ns.hash,
-	}
+func (e *statusError) Unwrap() error {
+	return e.err
+}
 
-	nsLen := ns.notes.Cardinality()
-	if nsLen == 0 {
-		return tc
+// ExitCode returns the exit status for err returned by the root command: 0 if
+// err is nil, the status of a statusError, or 1 for any other error.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
 	}
 
-	p := pool.NewWithResults[[]string]().WithMaxGoroutines(nsLen)
-
-	for n := range set.Elements(ns.notes) {
-		p.Go(func() []string {
-			f, err := os.ReadFile(n)
-			if err != nil {
-				log.Printf("failed to open file %s: %v", n, err)
-				return nil
-			}
-
-			tags, err := tagx.Extract(string(f))
-			if err != nil {
-				log.Printf("failed to extract tags from file %s: %v", n, err)
-				return nil
-			}
-
-			return tags
-		})
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code
 	}
+	return exitFailure
+}
 
-	res := p.Wait()
+// reportsStatus marks cmd as reporting its outcome with exit status 1, such as
+// diff --exit-code. Its errors, including usage errors, then exit with status
+// 2, so that scripts can tell them apart from its outcome.
+func reportsStatus(cmd *cobra.Command) {
+	fail := func(err error) error {
+		var se *statusError
+		if err == nil || errors.As(err, &se) {
+			return err
+		}
+		return &statusError{err: err, code: exitError}
+	}
 
-	// estimated total number of tags based on number of notes
-	m := make(map[string]int, nsLen*8)
-	total := 0
-	for _, tags := range res {
-		for _, t := range tags {
-			if ignoreFunc(t) {
-				continue
-			}
-			m[t]++
-			total++
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			return fail(args(cmd, a))
 		}
 	}
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return fail(run(cmd, args))
+	}
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return fail(err)
+	})
+}
 
-	tc.Tags = m
-	tc.Total = total
+// vaultFromArgs returns the path of the vault given as the first argument, or
+// OBSIDIAN_VAULT_PATH if no argument is given.
+func vaultFromArgs(args []string) (string, error) {
+	if len(args) == 0 {
+		p, exist := os.LookupEnv("OBSIDIAN_VAULT_PATH")
+		if !exist {
+			return "", fmt.Errorf("path not provided and OBSIDIAN_VAULT_PATH is not set")
+		}
+		return p, nil
+	}
 
-	return tc
+	return args[0], nil
 }
 
-func newTagCountsFromCache(root vaultPath) (tagCounts, error) {
-	var tc tagCounts
+// openVault returns a scanner for the vault at root, a directory or an
+// archive, configured from opts and any extra options. If opts.noCache is
+// true, the existing cache is ignored and every note is read. If opts.rev is
+// set, the vault is read as of that git revision. The returned scanner must be
+// closed.
+func openVault(root, version string, opts scanOptions, extra ...vault.Option) (*vault.Scanner, error) {
+	policy := vault.CacheReadWrite
+	if opts.noCache {
+		policy = vault.CacheRebuild
+	}
 
-	dataFile := root.cachePath()
-	f, err := os.Open(dataFile)
+	vopts := append([]vault.Option{
+		vault.WithVersion(version),
+		vault.WithCachePolicy(policy),
+		vault.WithJobs(opts.jobs),
+	}, extra...)
+
+	switch {
+	case opts.rev == "":
+		return vault.Open(root, vopts...)
+	case vault.IsArchive(root):
+		return nil, fmt.Errorf("--rev cannot be used with an archive")
+	default:
+		return vault.OpenRevision(root, opts.rev, vopts...)
+	}
+}
+
+// scanVault scans the vault at root with a scanner returned by openVault. The
+// returned scanner must be closed.
+//
+// The scan is aborted when ctx is done or opts.timeout elapses, in which case
+// the cache is left untouched.
+func scanVault(ctx context.Context, root, version string, opts scanOptions, extra ...vault.Option) (*vault.Scanner, *vault.Result, error) {
+	s, err := openVault(root, version, opts, extra...)
 	if err != nil {
-		return tc, err
+		return nil, nil, err
 	}
-	defer f.Close()
 
-	d := json.NewDecoder(f)
-	if err := d.Decode(&tc); err != nil {
-		return tc, err
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
 	}
-	return tc, nil
-}
 
-func (tc tagCounts) writeCache(root vaultPath) error {
-	f, err := os.OpenFile(root.cachePath(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	res, err := s.Scan(ctx)
 	if err != nil {
-		return err
+		s.Close()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, fmt.Errorf("scan did not complete within %s: %w", opts.timeout, err)
+		}
+		return nil, nil, err
 	}
-	defer f.Close()
 
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "\t")
-	if err := enc.Encode(tc); err != nil {
-		return err
+	return s, res, nil
+}
+
+func subcommands(cmd *cobra.Command) []string {
+	var subs []string
+	for _, c := range cmd.Commands() {
+		if !c.Hidden {
+			subs = append(subs, c.Name())
+		}
 	}
-	return nil
+	return subs
 }
 
-func (tc tagCounts) print(opts rootOptions) {
-	tc.fPrint(os.Stdout, opts)
+type tagCounts struct {
+	Tags  map[string]int
+	Total int
 }
 
-func (tc tagCounts) fPrint(w io.Writer, opts rootOptions) {
+// ranked returns the tag names sorted by descending count, with ties broken by
+// name, and truncated to limit. Non-positive limits mean all tags.
+func (tc tagCounts) ranked(limit int) []string {
 	names := slices.SortedFunc(maps.Keys(tc.Tags), func(a, b string) int {
-		return tc.Tags[b] - tc.Tags[a]
+		if c := tc.Tags[b] - tc.Tags[a]; c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
 	})
 
-	var limit int
-	if opts.limit <= 0 {
-		limit = len(names)
-	} else {
-		limit = min(len(names), opts.limit)
+	if limit <= 0 {
+		return names
 	}
+	return names[:min(len(names), limit)]
+}
+
+func (tc tagCounts) fPrint(w io.Writer, opts rootOptions) {
+	names := tc.ranked(opts.limit)
+	limit := len(names)
 
 	switch opts.displayMode {
 	case name:
@@ -266,109 +303,7 @@ func (tc tagCounts) fPrint(w io.Writer, opts rootOptions) {
 			fmt.Fprintf(w, "%.3f\t%s\n", freq, name)
 		}
 		w.Flush()
+	case tree:
+		tc.fPrintTree(w, opts.limit, opts.depth)
 	}
 }
-
-// vaultPath is a path to a valid directory.
-type vaultPath string
-
-func newVaultPath(path string) (vaultPath, error) {
-	info, err := os.Stat(path)
-	if err != nil {
-		return "", err
-	}
-	if !info.IsDir() {
-		return "", fmt.Errorf("%s is not a directory", path)
-	}
-	return vaultPath(path), nil
-}
-
-func (v vaultPath) String() string {
-	return string(v)
-}
-
-func (v vaultPath) excludePath() string {
-	return filepath.Join(v.String(), ".tobi.exclude")
-}
-
-func (v vaultPath) cachePath() string {
-	return filepath.Join(v.String(), ".tobi.json")
-}
-
-// noteSet represents a collection of discovered note files with cache validation.
-// The hash field is calculated from file paths and modification times to detect
-// changes in the vault for cache invalidation.
-type noteSet struct {
-	notes set.Set[string]
-	hash  uint64
-}
-
-// listNotes recursively traverses the directory at root and discovers all '.md' files
-// that should be tracked, filtering out files ignored by .gitignore patterns and
-// skipping the .git directory. It returns a noteSet containing the discovered files
-// and a hash calculated from file paths and modification times for cache validation.
-//
-// Files that cannot be accessed for file info are logged and skipped.
-//
-// Returns an error if the root path is invalid or .gitignore patterns cannot be read.
-func listNotes(root vaultPath) (noteSet, error) {
-	h := fnv.New64a()
-
-	absRoot, err := gitignore.NewAbsolutePath(string(root))
-	if err != nil {
-		return noteSet{}, err
-	}
-
-	m, err := gitignore.NewRepoRootMatcher(absRoot)
-	if err != nil {
-		return noteSet{}, err
-	}
-
-	notes := set.NewSet[string]()
-	err = filepath.WalkDir(absRoot.String(), func(path string, d fs.DirEntry, err error) error {
-		// Skip directory entry if there's an error
-		if err != nil {
-			return nil
-		}
-
-		// Skip .git directory
-		if d.IsDir() && d.Name() == ".git" {
-			return filepath.SkipDir
-		}
-
-		if d.Type().IsRegular() && filepath.Ext(path) == ".md" {
-			// Since root is absolute when we pass it to WalkDir, path is absolute.
-			// It's safe to construct AbsolutePath directly from path.
-			skip := m.MatchFile(gitignore.NewAbsolutePathUnchecked(path))
-			if skip {
-				return nil
-			}
-
-			info, err := d.Info()
-			// Skip files where we can't get info. Info() returns fs.ErrNotExist if the file
-			// has been removed or renamed since the directory read. Since we're only reading
-			// (not modifying files), this should never happen. However, we log the error
-			// as a safeguard to warn anyone against accidentally modifying files during traversal.
-			if err != nil {
-				log.Printf("failed to get file info for %s: %v", path, err)
-				return nil
-			}
-
-			// TODO: these 2 calls return errors, might need to handle them
-			_, _ = h.Write([]byte(path))
-			_ = binary.Write(h, binary.LittleEndian, info.ModTime().Unix())
-
-			notes.Add(path)
-		}
-
-		return nil
-	})
-	if err != nil {
-		return noteSet{}, err
-	}
-
-	return noteSet{
-		notes: notes,
-		hash:  h.Sum64(),
-	}, nil
-}
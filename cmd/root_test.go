@@ -1,359 +1,24 @@
 package cmd
 
 import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
-	set "github.com/deckarep/golang-set/v2"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/v3/fs"
 )
 
-func Test_listNotes(t *testing.T) {
-	testCases := []struct {
-		name string
-		dir  *fs.Dir
-		want []string
-	}{
-		{
-			name: "single",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile("note.md", "# Test"),
-			),
-			want: []string{"note.md"},
-		},
-		{
-			name: "multiple",
-			dir: fs.NewDir(t, "test",
-				fs.WithFiles(map[string]string{
-					"note1.md": "# Test 1",
-					"note2.md": "# Test 2",
-				}),
-			),
-			want: []string{"note1.md", "note2.md"},
-		},
-		{
-			name: "mixed file types",
-			dir: fs.NewDir(t, "test",
-				fs.WithFiles(map[string]string{
-					"note1.md": "# Test 1",
-					"t.txt":    "Text file",
-					"t.json":   `{"key": "value"}`,
-					"t.sh":     "#!/bin/bash",
-				}),
-			),
-			want: []string{"note1.md"},
-		},
-		{
-			name: "nested",
-			dir: fs.NewDir(t, "test",
-				fs.WithDir("level1",
-					fs.WithFile("note.md", "# Nested"),
-				),
-			),
-			want: []string{"level1/note.md"},
-		},
-		{
-			name: "deeply nested",
-			dir: fs.NewDir(t, "test",
-				fs.WithDir("level1",
-					fs.WithDir("level2",
-						fs.WithFile("note.md", "# Deep"),
-					),
-				),
-			),
-			want: []string{"level1/level2/note.md"},
-		},
-		{
-			name: ".git skipped",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile("note.md", "# Note"),
-				fs.WithDir(".git",
-					fs.WithFile("file.md", "# Ignored"),
-				),
-			),
-			want: []string{"note.md"},
-		},
-		{
-			name: "empty directory",
-			dir:  fs.NewDir(t, "test"),
-			want: []string{},
-		},
-		{
-			name: "root .gitignore",
-			dir: fs.NewDir(t, "test",
-				fs.WithFiles(map[string]string{
-					".gitignore": "level1/",
-					"note.md":    "content",
-				}),
-				fs.WithDir("level1",
-					fs.WithFiles(map[string]string{
-						"note2.md": "content",
-					}),
-				),
-			),
-			want: []string{"note.md"},
-		},
-		{
-			name: "subdir .gitignore",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile("note.md", "content"),
-				fs.WithDir("level1",
-					fs.WithFiles(map[string]string{
-						"note2.md": "content",
-					}),
-				),
-			),
-			want: []string{"note.md", "level1/note2.md"},
-		},
-		{
-			name: "multiple .gitignore",
-			dir: fs.NewDir(t, "test",
-				fs.WithFiles(map[string]string{
-					".gitignore": "level1/",
-					"note.md":    "content",
-				}),
-				fs.WithDir("level1",
-					fs.WithFiles(map[string]string{
-						"note2.md": "content",
-					}),
-				),
-				fs.WithDir("level2",
-					fs.WithFiles(map[string]string{
-						"note3.md":   "content",
-						"note4.md":   "content",
-						".gitignore": "note4.md",
-					}),
-				),
-			),
-			want: []string{"note.md", "level2/note3.md"},
-		},
-	}
-
-	r := require.New(t)
-	for _, tt := range testCases {
-		defer tt.dir.Remove()
-
-		t.Run(tt.name, func(_ *testing.T) {
-			root, err := newVaultPath(tt.dir.Path())
-			r.NoError(err)
-
-			ns, err := listNotes(root)
-			r.NoError(err)
-
-			// Convert absolute paths to relative paths for comparison
-			notes := set.Sorted(ns.notes)
-			relPaths := make([]string, len(notes))
-			for i, path := range notes {
-				relPath, err := filepath.Rel(root.String(), path)
-				r.NoError(err)
-				relPaths[i] = relPath
-			}
-
-			// Sort both slices for reliable comparison
-			sort.Strings(relPaths)
-			sort.Strings(tt.want)
-
-			r.Equal(tt.want, relPaths)
-		})
-	}
-}
-
-func Test_collectTags(t *testing.T) {
-	noIgnore := func(string) bool {
-		return false
-	}
-
-	testCases := []struct {
-		name      string
-		dir       *fs.Dir
-		filter    func(string) bool
-		want      map[string]int
-		wantTotal int
-	}{
-		{
-			name: "single file",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile("note1.md", "---\ntags: [golang, cobra]\n---\nContent #cli"),
-			),
-			filter: noIgnore,
-			want: map[string]int{
-				"golang": 1,
-				"cobra":  1,
-				"cli":    1,
-			},
-			wantTotal: 3,
-		},
-		{
-			name: "multiple files",
-			dir: fs.NewDir(t, "test",
-				fs.WithFiles(map[string]string{
-					"note1.md": "---\ntags: [golang, cobra]\n---\nContent",
-					"note2.md": "Content #cli #golang",
-					"note3.md": "---\ntags: [cobra]\n---\nContent",
-				}),
-			),
-			filter: noIgnore,
-			want: map[string]int{
-				"golang": 2,
-				"cobra":  2,
-				"cli":    1,
-			},
-			wantTotal: 5,
-		},
-		{
-			name: "with filter",
-			dir: fs.NewDir(t, "test",
-				fs.WithFiles(map[string]string{
-					"note1.md": "Content #daily #golang",
-					"note2.md": "---\ntags: [daily]\n---\nContent",
-				}),
-			),
-			filter: func(s string) bool {
-				return s == "daily"
-			},
-			want: map[string]int{
-				"golang": 1,
-			},
-			wantTotal: 1,
-		},
-		{
-			name: "skip files with errors",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile("invalid.md", "---\ntags: [invalid: yaml\n---\nContent"),
-			),
-			filter:    noIgnore, // shouldn't need this but include for completeness
-			want:      map[string]int{},
-			wantTotal: 0,
-		},
-		{
-			name: "ignore all tags",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile("note1.md", "---\ntags: [daily, personal]\n---\nContent"),
-			),
-			filter: func(string) bool {
-				return true
-			},
-			want:      map[string]int{},
-			wantTotal: 0,
-		},
-		{
-			name:      "empty noteSet",
-			dir:       fs.NewDir(t, "test"),
-			filter:    noIgnore, // shouldn't need this but include for completeness
-			want:      nil,
-			wantTotal: 0,
-		},
-	}
-
-	r := require.New(t)
-
-	for _, tt := range testCases {
-		defer tt.dir.Remove()
-
-		t.Run(tt.name, func(_ *testing.T) {
-			// Create noteSet from test directory
-			root, err := newVaultPath(tt.dir.Path())
-			r.NoError(err)
-			ns, err := listNotes(root)
-			r.NoError(err)
-
-			// Test collectTags
-			result := collectTags(ns, tt.filter)
-
-			// Verify results
-			r.Equal(tt.want, result.Tags)
-			r.Equal(tt.wantTotal, result.Total)
-			r.Equal(ns.hash, result.Hash) // Hash should be preserved
-		})
-	}
-}
-
-func Test_newTagCountsFromCache(t *testing.T) {
-	testCases := []struct {
-		name string
-		dir  *fs.Dir
-		want tagCounts
-	}{
-		{
-			name: "reads from .tobi.json",
-			dir: fs.NewDir(t, "test",
-				fs.WithFile(".tobi.json", `{"tags":{"golang":5,"cobra":3},"hash":12345678901234567890}`),
-			),
-			want: tagCounts{
-				Tags: map[string]int{
-					"golang": 5,
-					"cobra":  3,
-				},
-				Hash: 12345678901234567890,
-			},
-		},
-	}
-
-	r := require.New(t)
-
-	for _, tt := range testCases {
-		defer tt.dir.Remove()
-
-		t.Run(tt.name, func(_ *testing.T) {
-			root, err := newVaultPath(tt.dir.Path())
-			r.NoError(err)
-
-			result, err := newTagCountsFromCache(root)
-			r.NoError(err)
-
-			r.Equal(tt.want, result)
-		})
-	}
-}
-
-func Test_tagCounts_writeCache(t *testing.T) {
-	testCases := []struct {
-		name      string
-		tagCounts tagCounts
-		wantJSON  string
-	}{
-		{
-			name: "writes to .tobi.json with proper formatting",
-			tagCounts: tagCounts{
-				Tags: map[string]int{
-					"golang": 5,
-					"cobra":  3,
-				},
-				Hash:  12345678901234567890,
-				Total: 8,
-			},
-			wantJSON: "{\n\t\"tags\": {\n\t\t\"cobra\": 3,\n\t\t\"golang\": 5\n\t},\n\t\"hash\": 12345678901234567890,\n\t\"total\": 8\n}\n",
-		},
-	}
-
-	r := require.New(t)
-
-	for _, tt := range testCases {
-		t.Run(tt.name, func(_ *testing.T) {
-			dir := fs.NewDir(t, "test")
-			defer dir.Remove()
-
-			root, err := newVaultPath(dir.Path())
-			r.NoError(err)
-
-			// Write cache
-			err = tt.tagCounts.writeCache(root)
-			r.NoError(err)
-
-			// Verify file was created at correct location
-			content, err := os.ReadFile(root.cachePath())
-			r.NoError(err)
-
-			// Verify JSON content matches expected format
-			r.Equal(tt.wantJSON, string(content))
-		})
-	}
-}
-
 func Test_tagCounts_fPrint_limit(t *testing.T) {
 	// Common test data sorted by count: rust(150), golang(100), python(50)
 	common := tagCounts{
@@ -475,3 +140,244 @@ func Test_tagCounts_fPrint_displayMode(t *testing.T) {
 		})
 	}
 }
+
+func Test_rootCmd_cache(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"note1.md": "#golang #daily",
+			"note2.md": "#cobra",
+		}),
+	)
+	defer dir.Remove()
+
+	run := func() string {
+		var buf strings.Builder
+		c := NewRootCmd("test")
+		c.SetOut(&buf)
+		c.SetArgs([]string{dir.Path(), "--limit", "0"})
+		r.NoError(c.Execute())
+
+		lines := strings.Fields(buf.String())
+		sort.Strings(lines)
+		return strings.Join(lines, " ")
+	}
+
+	r.Equal("cobra daily golang", run())
+
+	// excludes are applied to cached tags
+	r.NoError(os.WriteFile(dir.Join(".tobi.exclude"), []byte("daily"), 0o644))
+	r.Equal("cobra golang", run())
+
+	// ignore rules are applied to cached notes
+	r.NoError(os.WriteFile(dir.Join(".tobiignore"), []byte("note2.md"), 0o644))
+	r.Equal("golang", run())
+
+	r.NoError(os.Remove(dir.Join(".tobiignore")))
+	r.NoError(os.Remove(dir.Join(".tobi.exclude")))
+	r.Equal("cobra daily golang", run())
+}
+
+func Test_rootCmd_canceled(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("note.md", "#golang"))
+	defer dir.Remove()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{dir.Path()})
+	r.ErrorIs(c.ExecuteContext(ctx), context.Canceled)
+
+	// a timed out scan fails too
+	c = NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{dir.Path(), "--timeout", "1ns"})
+	r.ErrorIs(c.Execute(), context.DeadlineExceeded)
+
+	// an aborted scan never writes the cache
+	r.NoFileExists(dir.Join(".tobi.json"))
+}
+
+func Test_rootCmd_archive(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test")
+	defer dir.Remove()
+
+	f, err := os.Create(dir.Join("vault.zip"))
+	r.NoError(err)
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"vault/note1.md":      "#golang #daily",
+		"vault/note2.md":      "#cobra #golang",
+		"vault/.tobi.exclude": "daily",
+	} {
+		w, err := zw.Create(name)
+		r.NoError(err)
+		_, err = io.WriteString(w, content)
+		r.NoError(err)
+	}
+	r.NoError(zw.Close())
+	r.NoError(f.Close())
+
+	var buf strings.Builder
+	c := NewRootCmd("test")
+	c.SetOut(&buf)
+	c.SetArgs([]string{dir.Join("vault.zip"), "--mode", "count"})
+	r.NoError(c.Execute())
+	r.Equal("2  golang\n1  cobra\n", buf.String())
+
+	// archives cannot be rewritten
+	c = NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{"rename", "golang", "go", dir.Join("vault.zip")})
+	r.ErrorIs(c.Execute(), vault.ErrReadOnly)
+
+	r.NoFileExists(dir.Join(".tobi.json"))
+}
+
+func Test_rootCmd_rev(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("note.md", "#golang #cli"))
+	defer dir.Remove()
+
+	repo, err := git.PlainInit(dir.Path(), false)
+	r.NoError(err)
+	wt, err := repo.Worktree()
+	r.NoError(err)
+	_, err = wt.Add("note.md")
+	r.NoError(err)
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	_, err = wt.Commit("initial", &git.CommitOptions{Author: sig})
+	r.NoError(err)
+
+	r.NoError(os.WriteFile(dir.Join("note.md"), []byte("#go"), 0o644))
+
+	var buf strings.Builder
+	c := NewRootCmd("test")
+	c.SetOut(&buf)
+	c.SetArgs([]string{dir.Path(), "--rev", "HEAD", "--mode", "count"})
+	r.NoError(c.Execute())
+	r.Equal("1  cli\n1  golang\n", buf.String())
+	r.NoFileExists(dir.Join(".tobi.json"))
+}
+
+func Test_tagCounts_fPrintTree(t *testing.T) {
+	tc := tagCounts{
+		Tags: map[string]int{
+			"golang":               1,
+			"golang/cobra":         2,
+			"golang/cobra/Command": 3,
+			"golang/testing":       1,
+			"proj/alpha":           4,
+			"proj/beta":            1,
+			"cli":                  2,
+		},
+		Total: 14,
+	}
+
+	testCases := []struct {
+		name     string
+		limit    int
+		depth    int
+		expected string
+	}{
+		{
+			name:  "full tree",
+			limit: 0,
+			depth: 0,
+			expected: "" +
+				"7  1  golang\n" +
+				"5  2  ├── cobra\n" +
+				"3  3  │   └── Command\n" +
+				"1  1  └── testing\n" +
+				"5  0  proj\n" +
+				"4  4  ├── alpha\n" +
+				"1  1  └── beta\n" +
+				"2  2  cli\n",
+		},
+		{
+			name:  "limited depth",
+			limit: 0,
+			depth: 1,
+			expected: "" +
+				"7  1  golang\n" +
+				"5  0  proj\n" +
+				"2  2  cli\n",
+		},
+		{
+			name:  "limited children per level",
+			limit: 1,
+			depth: 0,
+			expected: "" +
+				"7  1  golang\n" +
+				"5  2  └── cobra\n" +
+				"3  3      └── Command\n",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(*testing.T) {
+			var buf strings.Builder
+			opts := rootOptions{
+				limit:       tt.limit,
+				depth:       tt.depth,
+				displayMode: tree,
+			}
+			tc.fPrint(&buf, opts)
+
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func Test_ExitCode(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFile("a.md", "#golang"))
+	defer dir.Remove()
+
+	// run returns the error of the root command run with args
+	run := func(args ...string) error {
+		c := NewRootCmd("test")
+		c.SetOut(&strings.Builder{})
+		c.SetErr(&strings.Builder{})
+		c.SetArgs(args)
+		return c.Execute()
+	}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: 0},
+		{name: "tags differ", err: errTagsDiffer, expected: 1},
+		{name: "lint issues", err: errLintIssues, expected: 1},
+		{name: "wrapped status", err: fmt.Errorf("diff: %w", errTagsDiffer), expected: 1},
+		{name: "failure", err: errors.New("boom"), expected: 1},
+		{name: "missing vault", err: run(dir.Join("missing")), expected: 1},
+		{name: "unknown flag", err: run("--no-such-flag", dir.Path()), expected: 1},
+		{name: "diff unknown revision", err: run("diff", "no-such-rev", "--vault", dir.Path(), "--exit-code"), expected: 2},
+		{name: "diff unknown flag", err: run("diff", "HEAD", "--no-such-flag"), expected: 2},
+		{name: "diff too many arguments", err: run("diff", "a", "b", "c"), expected: 2},
+		{name: "lint missing vault", err: run("lint", dir.Join("missing")), expected: 2},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.Equal(tt.expected, ExitCode(tt.err))
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+type serveOptions struct {
+	addr     string
+	debounce time.Duration
+	scan     scanOptions
+}
+
+func newServeCmd(version string) *cobra.Command {
+	var opts serveOptions
+
+	cmd := &cobra.Command{
+		Use:   "serve [path]",
+		Short: "Serve the tags of a vault over a local HTTP API",
+		Args:  cobra.RangeArgs(0, 1),
+		Example: `
+		# serve a vault on port 7777
+		tobi serve /path/to/your/vault --addr 127.0.0.1:7777
+
+		# then query it
+		curl 'http://127.0.0.1:7777/api/tags?prefix=golang&limit=5'
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, err := openVault(root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			ln, err := net.Listen("tcp", opts.addr)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			ts := newTagServer(sc.Root())
+			srv := &http.Server{
+				Handler:           ts.handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			errc := make(chan error, 2)
+			go func() { errc <- ts.refresh(ctx, sc, opts.debounce) }()
+			go func() { errc <- srv.Serve(ln) }()
+			fmt.Fprintf(cmd.ErrOrStderr(), "serving %s on http://%s\n", sc.Root(), ln.Addr())
+
+			select {
+			case <-ctx.Done():
+			case err = <-errc:
+			}
+
+			shutdown, stop := context.WithTimeout(context.Background(), 5*time.Second)
+			defer stop()
+			if serr := srv.Shutdown(shutdown); err == nil {
+				err = serr
+			}
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&opts.addr, "addr", "a", "127.0.0.1:7777", "address to listen on")
+	flags.DurationVar(&opts.debounce, "debounce", 200*time.Millisecond, "time to wait for changes to settle before updating")
+	addRevFlag(cmd, &opts.scan)
+	addJobsFlag(cmd, &opts.scan)
+	addNoCacheFlag(cmd, &opts.scan)
+
+	return cmd
+}
+
+// tagServer serves the latest scan result of a vault as JSON.
+type tagServer struct {
+	vault string
+
+	mu  sync.RWMutex
+	res *vault.Result
+	// scanned is the time of the latest scan.
+	scanned time.Time
+	// scans is the number of scans, including updates of a watched vault.
+	scans int
+}
+
+func newTagServer(vault string) *tagServer {
+	return &tagServer{vault: vault}
+}
+
+// refresh scans the vault, then keeps the result up to date until ctx is
+// done. Vaults that cannot be watched, such as archives and git revisions,
+// are scanned once.
+func (ts *tagServer) refresh(ctx context.Context, sc *vault.Scanner, debounce time.Duration) error {
+	err := sc.Watch(ctx, debounce, ts.set)
+	if !errors.Is(err, vault.ErrReadOnly) {
+		return err
+	}
+
+	res, err := sc.Scan(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ts.set(res); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// set replaces the served result.
+func (ts *tagServer) set(res *vault.Result) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.res = res
+	ts.scanned = time.Now()
+	ts.scans++
+	return nil
+}
+
+// result returns the served result, or nil if the first scan is not done.
+func (ts *tagServer) result() *vault.Result {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.res
+}
+
+func (ts *tagServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", ts.handleStatus)
+	mux.Handle("GET /api/tags", ts.ready(ts.handleTags))
+	mux.Handle("GET /api/tree", ts.ready(ts.handleTree))
+	mux.Handle("GET /api/notes", ts.ready(ts.handleNotes))
+	mux.Handle("GET /api/related", ts.ready(ts.handleRelated))
+	return mux
+}
+
+// resultHandler handles a request with the served result.
+type resultHandler func(w http.ResponseWriter, r *http.Request, res *vault.Result) error
+
+// ready adapts h to an http.Handler, which answers 503 Service Unavailable
+// until the first scan is done. Errors returned by h are written as JSON
+// with their status code.
+func (ts *tagServer) ready(h resultHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := ts.result()
+		if res == nil {
+			writeError(w, httpError{http.StatusServiceUnavailable, "vault is being scanned"})
+			return
+		}
+		if err := h(w, r, res); err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+// statusReport is the document served by /api/status.
+type statusReport struct {
+	Vault string `json:"vault"`
+	// Ready is false until the first scan is done.
+	Ready bool `json:"ready"`
+	Notes int  `json:"notes"`
+	Tags  int  `json:"tags"`
+	Total int  `json:"total"`
+	// ScannedAt is the time of the latest scan, omitted until the first scan
+	// is done.
+	ScannedAt *time.Time `json:"scannedAt,omitempty"`
+	Scans     int        `json:"scans"`
+}
+
+func (ts *tagServer) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	ts.mu.RLock()
+	s := statusReport{Vault: ts.vault, Scans: ts.scans}
+	if ts.res != nil {
+		scanned := ts.scanned
+		s.Ready = true
+		s.Notes = len(ts.res.Notes)
+		s.Tags = len(ts.res.Counts)
+		s.Total = ts.res.Total
+		s.ScannedAt = &scanned
+	}
+	ts.mu.RUnlock()
+
+	writeJSON(w, s)
+}
+
+// handleTags serves the tags with their counts, like tobi --format json.
+// Query parameters: prefix keeps a tag and the tags nested under it, min
+// keeps tags used at least that many times, and limit keeps the most used
+// tags.
+func (ts *tagServer) handleTags(w http.ResponseWriter, r *http.Request, res *vault.Result) error {
+	q := r.URL.Query()
+	limit, err := intParam(q, "limit", 0)
+	if err != nil {
+		return err
+	}
+	minCount, err := intParam(q, "min", 0)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimPrefix(q.Get("prefix"), "#")
+
+	tc := filterTags(res, prefix, minCount)
+	writeJSON(w, tc.report(newScanInfo(res), limit))
+	return nil
+}
+
+// filterTags returns the counts of the tags of res used at least minCount
+// times and, if prefix is not empty, equal to or nested under prefix. The
+// total is left unchanged, so that relative frequencies are relative to all
+// tags.
+func filterTags(res *vault.Result, prefix string, minCount int) tagCounts {
+	tc := tagCounts{Tags: make(map[string]int), Total: res.Total}
+	for t, c := range res.Counts {
+		if c < minCount || (prefix != "" && t != prefix && !tagx.IsDescendant(t, prefix)) {
+			continue
+		}
+		tc.Tags[t] = c
+	}
+	return tc
+}
+
+// handleTree serves the tag hierarchy. Query parameters: depth keeps tags
+// nested at most that deep.
+func (ts *tagServer) handleTree(w http.ResponseWriter, r *http.Request, res *vault.Result) error {
+	depth, err := intParam(r.URL.Query(), "depth", 0)
+	if err != nil {
+		return err
+	}
+
+	tree := tagx.NewTagTree(res.Counts)
+	if depth > 0 {
+		pruneTree(tree, depth)
+	}
+
+	writeJSON(w, tree)
+	return nil
+}
+
+// pruneTree drops the nodes nested deeper than depth under n. Rolled-up
+// counts are left unchanged.
+func pruneTree(n *tagx.TagNode, depth int) {
+	if depth == 0 {
+		n.Children = nil
+		return
+	}
+	for _, c := range n.Children {
+		pruneTree(c, depth-1)
+	}
+}
+
+// handleNotes serves the notes carrying a tag, like tobi notes --format json.
+// Query parameters: tag is required, descendants includes notes carrying tags
+// nested under it, and limit keeps the notes carrying it most.
+func (ts *tagServer) handleNotes(w http.ResponseWriter, r *http.Request, res *vault.Result) error {
+	q := r.URL.Query()
+	tag, err := tagParam(q)
+	if err != nil {
+		return err
+	}
+	descendants, err := boolParam(q, "descendants")
+	if err != nil {
+		return err
+	}
+	limit, err := intParam(q, "limit", 0)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, newNotesReport(res, tag, descendants, limit))
+	return nil
+}
+
+// handleRelated serves the tags used together with a tag, like tobi related
+// --format json. Query parameters: tag is required, sort is jaccard, notes or
+// pmi, min_notes keeps pairs carried by at least that many notes, and limit
+// defaults to 8.
+func (ts *tagServer) handleRelated(w http.ResponseWriter, r *http.Request, res *vault.Result) error {
+	q := r.URL.Query()
+	tag, err := tagParam(q)
+	if err != nil {
+		return err
+	}
+	minNotes, err := intParam(q, "min_notes", 1)
+	if err != nil {
+		return err
+	}
+	limit, err := intParam(q, "limit", 8)
+	if err != nil {
+		return err
+	}
+
+	by := byJaccard
+	if s := q.Get("sort"); s != "" {
+		var ok bool
+		if by, ok = enumValue(pairSortIDs, s); !ok {
+			return httpError{http.StatusBadRequest, fmt.Sprintf("invalid sort %q", s)}
+		}
+	}
+
+	co := vault.NewCooccurrence(res.Notes)
+	pairs := co.Related(tag, minNotes)
+	sortPairs(pairs, by)
+	if limit > 0 {
+		pairs = pairs[:min(len(pairs), limit)]
+	}
+
+	report := relatedReport{
+		Vault:   res.Root,
+		Notes:   len(res.Notes),
+		Tag:     tag,
+		Tagged:  co.Notes(tag),
+		Related: make([]relatedTag, 0, len(pairs)),
+	}
+	for _, p := range pairs {
+		report.Related = append(report.Related, relatedTag{
+			Tag:     p.B,
+			Notes:   p.Notes,
+			Jaccard: p.Jaccard,
+			PMI:     p.PMI,
+		})
+	}
+
+	writeJSON(w, report)
+	return nil
+}
+
+// enumValue returns the value of an enum flag whose variants include s.
+func enumValue[E comparable](ids map[E][]string, s string) (E, bool) {
+	for v, names := range ids {
+		for _, n := range names {
+			if n == s {
+				return v, true
+			}
+		}
+	}
+	var zero E
+	return zero, false
+}
+
+// httpError is an error answered with its status code.
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e httpError) Error() string {
+	return e.msg
+}
+
+// intParam returns the integer query parameter name, or def if it is not set.
+func intParam(q url.Values, name string, def int) (int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, httpError{http.StatusBadRequest, fmt.Sprintf("invalid %s %q", name, s)}
+	}
+	return n, nil
+}
+
+// boolParam returns the boolean query parameter name, false if it is not set.
+func boolParam(q url.Values, name string) (bool, error) {
+	s := q.Get(name)
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, httpError{http.StatusBadRequest, fmt.Sprintf("invalid %s %q", name, s)}
+	}
+	return b, nil
+}
+
+// tagParam returns the required tag query parameter, with an optional leading
+// '#'.
+func tagParam(q url.Values) (string, error) {
+	tag := strings.TrimPrefix(q.Get("tag"), "#")
+	if tag == "" {
+		return "", httpError{http.StatusBadRequest, "missing tag"}
+	}
+	if !tagx.IsValid(tag) {
+		return "", httpError{http.StatusBadRequest, fmt.Sprintf("invalid tag %q", tag)}
+	}
+	return tag, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON object with an error field. Errors other
+// than httpError are internal server errors.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he httpError
+	if errors.As(err, &he) {
+		status = he.status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_tagServer(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("a.md", "#golang #golang/cobra"),
+		fs.WithFile("b.md", "#golang/cobra #cli"),
+		fs.WithFile("c.md", "#rust #cli #daily"),
+		fs.WithFile(vault.ExcludeFile, "daily"),
+	)
+	defer dir.Remove()
+
+	r := require.New(t)
+
+	ts := newTagServer(dir.Path())
+	srv := httptest.NewServer(ts.handler())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		r.NoError(err)
+		defer resp.Body.Close()
+
+		r.Equal("application/json", resp.Header.Get("Content-Type"))
+		b, err := io.ReadAll(resp.Body)
+		r.NoError(err)
+		return resp.StatusCode, string(b)
+	}
+
+	// nothing is served until the first scan is done
+	status, body := get("/api/tags")
+	r.Equal(http.StatusServiceUnavailable, status)
+	r.JSONEq(`{"error":"vault is being scanned"}`, body)
+
+	status, body = get("/api/status")
+	r.Equal(http.StatusOK, status)
+	r.JSONEq(`{"vault":"`+dir.Path()+`","ready":false,"notes":0,"tags":0,"total":0,"scans":0}`, body)
+
+	sc, err := vault.NewScanner(dir.Path(), vault.WithExcludeFile(), vault.WithCachePolicy(vault.CacheDisabled))
+	r.NoError(err)
+	res, err := sc.Scan(t.Context())
+	r.NoError(err)
+	r.NoError(ts.set(res))
+
+	testCases := []struct {
+		name     string
+		path     string
+		status   int
+		expected string
+	}{
+		{
+			name:   "tags",
+			path:   "/api/tags?limit=2",
+			status: http.StatusOK,
+			expected: `{"vault":"` + dir.Path() + `","notes":3,"total":6,"cacheHit":false,"tags":[
+				{"tag":"cli","count":2,"relative":33.33333333333333},
+				{"tag":"golang/cobra","count":2,"relative":33.33333333333333}
+			]}`,
+		},
+		{
+			name:   "tags with prefix and min",
+			path:   "/api/tags?prefix=%23golang&min=1",
+			status: http.StatusOK,
+			expected: `{"vault":"` + dir.Path() + `","notes":3,"total":6,"cacheHit":false,"tags":[
+				{"tag":"golang/cobra","count":2,"relative":33.33333333333333},
+				{"tag":"golang","count":1,"relative":16.666666666666664}
+			]}`,
+		},
+		{
+			name:   "tree",
+			path:   "/api/tree?depth=1",
+			status: http.StatusOK,
+			expected: `{"name":"","tag":"","count":0,"total":6,"children":[
+				{"name":"golang","tag":"golang","count":1,"total":3},
+				{"name":"cli","tag":"cli","count":2,"total":2},
+				{"name":"rust","tag":"rust","count":1,"total":1}
+			]}`,
+		},
+		{
+			name:   "notes",
+			path:   "/api/notes?tag=golang&descendants=true",
+			status: http.StatusOK,
+			expected: `{"vault":"` + dir.Path() + `","notes":3,"total":3,"cacheHit":false,"tag":"golang","descendants":true,"matches":[
+				{"path":"a.md","count":2},
+				{"path":"b.md","count":1}
+			]}`,
+		},
+		{
+			name:     "notes of unknown tag",
+			path:     "/api/notes?tag=missing",
+			status:   http.StatusOK,
+			expected: `{"vault":"` + dir.Path() + `","notes":3,"total":0,"cacheHit":false,"tag":"missing","descendants":false,"matches":[]}`,
+		},
+		{
+			name:   "related",
+			path:   "/api/related?tag=cli&sort=notes",
+			status: http.StatusOK,
+			expected: `{"vault":"` + dir.Path() + `","notes":3,"tag":"cli","tagged":2,"related":[
+				{"tag":"golang/cobra","notes":1,"jaccard":0.3333333333333333,"pmi":-0.41503749927884376},
+				{"tag":"rust","notes":1,"jaccard":0.5,"pmi":0.5849625007211563}
+			]}`,
+		},
+		{
+			name:     "missing tag",
+			path:     "/api/related",
+			status:   http.StatusBadRequest,
+			expected: `{"error":"missing tag"}`,
+		},
+		{
+			name:     "invalid parameter",
+			path:     "/api/tags?limit=ten",
+			status:   http.StatusBadRequest,
+			expected: `{"error":"invalid limit \"ten\""}`,
+		},
+		{
+			name:     "invalid sort",
+			path:     "/api/related?tag=cli&sort=size",
+			status:   http.StatusBadRequest,
+			expected: `{"error":"invalid sort \"size\""}`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			status, body := get(tt.path)
+			r.Equal(tt.status, status)
+			r.JSONEq(tt.expected, body)
+		})
+	}
+
+	status, body = get("/api/status")
+	r.Equal(http.StatusOK, status)
+	r.Contains(body, `"ready":true,"notes":3,"tags":4,"total":6,"scannedAt":"`)
+	r.True(strings.HasSuffix(body, `"scans":1}`+"\n"))
+}
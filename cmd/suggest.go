@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+type suggestOptions struct {
+	vault  string
+	limit  int
+	format outputFormat
+	scan   scanOptions
+}
+
+func newSuggestCmd(version string) *cobra.Command {
+	var opts suggestOptions
+
+	cmd := &cobra.Command{
+		Use:   "suggest [note]",
+		Short: "Suggest existing tags for a note or a text",
+		Long: `Suggest existing tags for a note or a text.
+
+Tags are ranked by the TF-IDF similarity between the text and the notes
+carrying every tag, and by how often they are used together with the tags the
+text already has. Only tags used in the vault are suggested, and nothing is
+sent over the network.
+
+The text is read from note, or from stdin if note is omitted or "-". A note of
+the vault is compared with every other note.`,
+		Args: cobra.RangeArgs(0, 1),
+		Example: `
+		# suggest tags for a note of the vault
+		tobi suggest notes/draft.md --vault /path/to/your/vault
+
+		# suggest tags for any text
+		pbpaste | tobi suggest --limit 3
+
+		# list suggestions for the notes staged in git, in a pre-commit hook
+		git diff --cached --name-only -- '*.md' | xargs -n 1 tobi suggest --format tsv
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var vaultArgs []string
+			if opts.vault != "" {
+				vaultArgs = append(vaultArgs, opts.vault)
+			}
+			root, err := vaultFromArgs(vaultArgs)
+			if err != nil {
+				return err
+			}
+
+			var note string
+			if len(args) == 1 && args[0] != "-" {
+				note = args[0]
+			}
+			text, err := readInput(cmd.InOrStdin(), note)
+			if err != nil {
+				return err
+			}
+			sc, res, err := scanVault(cmd.Context(), root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			// a note must not be described by its own text
+			notes := res.Notes
+			if rel, ok := vaultRel(res.Root, note); ok {
+				notes = slices.DeleteFunc(slices.Clone(notes), func(n vault.Note) bool {
+					return n.Path == rel
+				})
+			}
+
+			report, err := suggestTags(cmd.Context(), sc, res, notes, text, opts.limit)
+			if err != nil {
+				return err
+			}
+
+			return report.render(cmd.OutOrStdout(), opts.format)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&opts.vault, "vault", "V", "", "path to the vault. Defaults to OBSIDIAN_VAULT_PATH.")
+	flags.IntVarP(&opts.limit, "limit", "l", 8, "number of tags to suggest. Non-positive values mean all.")
+	addFormatFlag(cmd, &opts.format)
+	addScanFlags(cmd, &opts.scan)
+
+	return cmd
+}
+
+// suggestTags suggests existing tags of the vault scanned in res for text,
+// drawn from notes, and returns at most limit of them; non-positive values of
+// limit mean all. The tags text already has are never suggested.
+func suggestTags(ctx context.Context, sc *vault.Scanner, res *vault.Result, notes []vault.Note, text string, limit int) (suggestReport, error) {
+	tags, err := tagx.Extract(text)
+	if err != nil {
+		return suggestReport{}, fmt.Errorf("failed to extract tags: %w", err)
+	}
+	tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	if tags == nil {
+		tags = []string{}
+	}
+
+	sg, err := sc.Suggester(ctx, notes)
+	if err != nil {
+		return suggestReport{}, err
+	}
+
+	report := suggestReport{
+		Vault:       res.Root,
+		Notes:       len(notes),
+		Tags:        tags,
+		Suggestions: []suggestedTag{},
+	}
+	for _, s := range sg.Suggest(text, tags, limit) {
+		report.Suggestions = append(report.Suggestions, suggestedTag{
+			Tag:          s.Tag,
+			Count:        res.Counts[s.Tag],
+			Score:        s.Score,
+			Similarity:   s.Similarity,
+			Cooccurrence: s.Cooccurrence,
+		})
+	}
+	return report, nil
+}
+
+// readInput returns the content of the file at path, or of r if path is empty.
+func readInput(r io.Reader, path string) (string, error) {
+	if path == "" {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return string(b), nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// vaultRel returns the slash-separated path of path relative to the vault at
+// root, and whether path is inside the vault.
+func vaultRel(root, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// suggestedTag is an existing tag suggested for the text.
+type suggestedTag struct {
+	Tag string `json:"tag" yaml:"tag"`
+	// Count is the number of occurrences of the tag in the vault.
+	Count        int     `json:"count" yaml:"count"`
+	Score        float64 `json:"score" yaml:"score"`
+	Similarity   float64 `json:"similarity" yaml:"similarity"`
+	Cooccurrence float64 `json:"cooccurrence" yaml:"cooccurrence"`
+}
+
+// suggestReport is the document written by the json and yaml formats.
+type suggestReport struct {
+	Vault string `json:"vault" yaml:"vault"`
+	// Notes is the number of notes the suggestions are drawn from.
+	Notes int `json:"notes" yaml:"notes"`
+	// Tags are the tags the text already has.
+	Tags        []string       `json:"tags" yaml:"tags"`
+	Suggestions []suggestedTag `json:"suggestions" yaml:"suggestions"`
+}
+
+type suggestRecord struct {
+	Type string `json:"type"`
+	suggestedTag
+}
+
+func (r suggestReport) render(w io.Writer, format outputFormat) error {
+	switch format {
+	case textFormat:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, t := range r.Suggestions {
+			fmt.Fprintf(tw, "%.2f\t%s\n", t.Score, t.Tag)
+		}
+		return tw.Flush()
+	case jsonFormat, yamlFormat:
+		return writeDocument(w, format, r)
+	case ndjsonFormat:
+		records := make([]any, 0, len(r.Suggestions))
+		for _, t := range r.Suggestions {
+			records = append(records, suggestRecord{Type: "suggestion", suggestedTag: t})
+		}
+		return writeRecords(w, records)
+	case csvFormat, tsvFormat:
+		rows := make([][]string, 0, len(r.Suggestions))
+		for _, t := range r.Suggestions {
+			rows = append(rows, []string{
+				t.Tag,
+				strconv.Itoa(t.Count),
+				strconv.FormatFloat(t.Score, 'f', -1, 64),
+				strconv.FormatFloat(t.Similarity, 'f', -1, 64),
+				strconv.FormatFloat(t.Cooccurrence, 'f', -1, 64),
+			})
+		}
+		return writeTable(w, format, []string{"tag", "count", "score", "similarity", "cooccurrence"}, rows)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_suggestCmd(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("go.md", "Goroutines and channels make concurrency simple. #golang"),
+		fs.WithFile("cobra.md", "Cobra commands, flags and completions for a CLI. #golang #cli"),
+		fs.WithFile("clap.md", "Clap parses flags and subcommands of a CLI. #rust #cli"),
+		fs.WithFile("borrow.md", "The borrow checker and lifetimes. #rust"),
+		fs.WithFile("draft.md", "Subcommands and flags of a Cobra CLI. #golang"),
+	)
+	defer dir.Remove()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "stdin",
+			stdin:    "Channels between goroutines",
+			expected: "0.25  golang\n",
+		},
+		{
+			name:     "dash reads stdin",
+			args:     []string{"-", "--limit", "1"},
+			stdin:    "Lifetimes and the borrow checker of a CLI",
+			expected: "0.74  rust\n",
+		},
+		{
+			name:     "unknown terms",
+			stdin:    "Sourdough bread recipe",
+			expected: "",
+		},
+		{
+			name:     "note of the vault",
+			args:     []string{dir.Join("draft.md")},
+			expected: "0.62  cli\n0.35  rust\n",
+		},
+		{
+			name: "json",
+			args: []string{dir.Join("draft.md"), "--format", "json", "--limit", "1"},
+			expected: `{
+  "vault": "` + dir.Path() + `",
+  "notes": 4,
+  "tags": [
+    "golang"
+  ],
+  "suggestions": [
+    {
+      "tag": "cli",
+      "count": 2,
+      "score": 0.6218002906166725,
+      "similarity": 0.7454289865952465,
+      "cooccurrence": 0.3333333333333333
+    }
+  ]
+}
+`,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			var buf strings.Builder
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetIn(strings.NewReader(tt.stdin))
+			c.SetArgs(append([]string{"suggest", "--vault", dir.Path()}, tt.args...))
+
+			r.NoError(c.Execute())
+			r.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func Test_suggestCmd_ErrorCases(t *testing.T) {
+	dir := fs.NewDir(t, "test", fs.WithFile("a.md", "#golang"))
+	defer dir.Remove()
+
+	r := require.New(t)
+
+	c := NewRootCmd("test")
+	c.SetOut(&strings.Builder{})
+	c.SetErr(&strings.Builder{})
+	c.SetArgs([]string{"suggest", dir.Join("missing.md"), "--vault", dir.Path()})
+	r.Error(c.Execute())
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+)
+
+// fPrintTree writes the tag hierarchy as a tree drawn with box-drawing
+// characters. Each line shows the rolled-up count of a tag, that is the usages
+// of the tag and all of its descendants, followed by its own count.
+//
+// At each level, only the limit tags with the highest rolled-up counts are
+// shown. Tags nested deeper than depth are hidden, but still counted in the
+// rolled-up counts of their ancestors. Non-positive values mean no limit.
+func (tc tagCounts) fPrintTree(w io.Writer, limit, depth int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	var walk func(n *tagx.TagNode, prefix string, level int)
+	walk = func(n *tagx.TagNode, prefix string, level int) {
+		children := n.Children
+		if limit > 0 {
+			children = children[:min(len(children), limit)]
+		}
+
+		for i, c := range children {
+			var branch, indent string
+			switch {
+			case level == 1:
+				// top-level tags are not connected to each other
+			case i == len(children)-1:
+				branch, indent = "└── ", "    "
+			default:
+				branch, indent = "├── ", "│   "
+			}
+
+			fmt.Fprintf(tw, "%d\t%d\t%s%s%s\n", c.Total, c.Count, prefix, branch, c.Name)
+
+			if depth <= 0 || level < depth {
+				walk(c, prefix+indent, level+1)
+			}
+		}
+	}
+
+	walk(tagx.NewTagTree(tc.Tags), "", 1)
+	tw.Flush()
+}
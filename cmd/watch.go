@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nt54hamnghi/tobi/pkg/vault"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\x1b[H\x1b[2J"
+
+type watchOptions struct {
+	limit       int
+	depth       int
+	displayMode displayMode
+	events      bool
+	debounce    time.Duration
+	scan        scanOptions
+}
+
+func newWatchCmd(version string) *cobra.Command {
+	var opts watchOptions
+
+	cmd := &cobra.Command{
+		Use:   "watch [path]",
+		Short: "Keep tag counts up to date as the vault changes",
+		Args:  cobra.RangeArgs(0, 1),
+		Example: `
+		# keep the top 20 tags, with counts, in a terminal pane
+		tobi watch /path/to/your/vault --limit 20 --mode count
+
+		# stream tag changes as NDJSON
+		tobi watch --events | jq -c 'select(.type == "added")'
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := vaultFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			sc, err := openVault(root, version, opts.scan, vault.WithExcludeFile())
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+
+			w := cmd.OutOrStdout()
+			view := watchView{w: w, opts: opts, clear: isTerminal(w)}
+			err = sc.Watch(cmd.Context(), opts.debounce, view.update)
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			if errors.Is(err, vault.ErrReadOnly) {
+				return fmt.Errorf("cannot watch %s: %w", sc.Root(), err)
+			}
+			return err
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+	flags.IntVarP(&opts.limit, "limit", "l", 8, "number of tags to display. Non-positive values mean all.")
+	flags.VarP(
+		enumflag.New(&opts.displayMode, "mode", displayModeIDs, enumflag.EnumCaseSensitive),
+		"mode", "m", displayModeUsage(),
+	)
+	flags.IntVarP(&opts.depth, "depth", "d", 0, "maximum depth of the tree in tree mode. Non-positive values mean unlimited.")
+	flags.BoolVarP(&opts.events, "events", "e", false, "print tag changes as NDJSON records instead of redrawing the tags")
+	flags.DurationVar(&opts.debounce, "debounce", 200*time.Millisecond, "time to wait for changes to settle before updating")
+	addJobsFlag(cmd, &opts.scan)
+	addNoCacheFlag(cmd, &opts.scan)
+
+	if err := cmd.RegisterFlagCompletionFunc("mode", completeDisplayModeFlag); err != nil {
+		os.Exit(1)
+	}
+
+	return cmd
+}
+
+// watchView writes every result of a watched vault.
+type watchView struct {
+	w    io.Writer
+	opts watchOptions
+	// clear is true if the output is redrawn in place.
+	clear bool
+	// prev is the previous result, or nil before the first one.
+	prev *vault.Result
+}
+
+// update writes res. Tags are redrawn in full, or, with --events, only the
+// changes since the previous result are written, in the ndjson format of the
+// diff command. The first result is diffed against an empty vault.
+func (v *watchView) update(res *vault.Result) error {
+	first := v.prev == nil
+	prev := v.prev
+	if first {
+		prev = &vault.Result{Root: res.Root}
+	}
+	v.prev = res
+
+	if v.opts.events {
+		return diffCounts(prev, res).render(v.w, ndjsonFormat)
+	}
+
+	switch {
+	case v.clear:
+		io.WriteString(v.w, clearScreen)
+	case !first:
+		// separate successive results when the output is not a terminal
+		fmt.Fprintln(v.w)
+	}
+	fmt.Fprintf(v.w, "%s: %d notes, %d tags, updated %s\n\n",
+		res.Root, len(res.Notes), len(res.Counts), time.Now().Format(time.TimeOnly))
+
+	tc := tagCounts{Tags: res.Counts, Total: res.Total}
+	tc.fPrint(v.w, rootOptions{
+		limit:       v.opts.limit,
+		depth:       v.opts.depth,
+		displayMode: v.opts.displayMode,
+	})
+	return nil
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+// syncBuilder is a strings.Builder safe for concurrent use.
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (s *syncBuilder) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuilder) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func Test_watchCmd(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		until    string
+		expected []string
+	}{
+		{
+			name:  "events",
+			args:  []string{"--events"},
+			until: `"changed"`,
+			expected: []string{
+				`{"type":"added","tag":"cli","from":0,"to":1,"delta":1}`,
+				`{"type":"added","tag":"golang","from":0,"to":1,"delta":1}`,
+				`{"type":"removed","tag":"cli","from":1,"to":0,"delta":-1}`,
+				`{"type":"changed","tag":"golang","from":1,"to":2,"delta":1}`,
+			},
+		},
+		{
+			name:  "redraw",
+			args:  []string{"--mode", "count"},
+			until: "2  golang",
+			expected: []string{
+				"1 notes, 2 tags, updated ",
+				"1  cli\n1  golang\n",
+				"2 notes, 1 tags, updated ",
+				"2  golang\n",
+			},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			dir := fs.NewDir(t, "test", fs.WithFile("a.md", "#golang #cli"))
+			defer dir.Remove()
+
+			var buf syncBuilder
+			ctx, cancel := context.WithCancel(t.Context())
+			c := NewRootCmd("test")
+			c.SetOut(&buf)
+			c.SetArgs(append([]string{"watch", dir.Path(), "--debounce", "20ms"}, tt.args...))
+
+			done := make(chan error)
+			go func() { done <- c.ExecuteContext(ctx) }()
+
+			r.Eventually(func() bool { return strings.Contains(buf.String(), "golang") }, 5*time.Second, 10*time.Millisecond)
+			r.NoError(os.WriteFile(dir.Join("a.md"), []byte("#golang"), 0o644))
+			r.NoError(os.WriteFile(dir.Join("b.md"), []byte("#golang"), 0o644))
+			r.Eventually(func() bool { return strings.Contains(buf.String(), tt.until) }, 5*time.Second, 10*time.Millisecond)
+
+			cancel()
+			r.NoError(<-done)
+
+			out := buf.String()
+			last := 0
+			for _, e := range tt.expected {
+				i := strings.Index(out[last:], e)
+				r.GreaterOrEqual(i, 0, "%q not found after offset %d in %q", e, last, out)
+				last += i + len(e)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/charmbracelet/fang"
 	"github.com/nt54hamnghi/tobi/cmd"
@@ -11,10 +13,19 @@ import (
 const VERSION = "0.1.5"
 
 func main() {
-	c := cmd.NewRootCmd()
-	if err := fang.Execute(context.Background(), c,
+	// cancel the command on the first SIGINT or SIGTERM, so that scans stop
+	// cleanly, and restore the default behavior to let a second signal kill it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	c := cmd.NewRootCmd(VERSION)
+	if err := fang.Execute(ctx, c,
 		fang.WithVersion(VERSION),
 	); err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
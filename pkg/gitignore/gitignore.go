@@ -2,10 +2,13 @@ package gitignore
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
@@ -18,16 +21,16 @@ const (
 	tobiignoreFile = ".tobiignore"
 )
 
-// .git/info/exclude
-var infoExcludeFile = filepath.Join(gitDir, "info", "exclude")
+// .git/info/exclude, .git/info/exclude patterns apply to the repository root
+var infoExcludeFile = path.Join(gitDir, "info", "exclude")
 
 type RepoRootMatcher struct {
 	Root AbsolutePath
 	gitignore.Matcher
 }
 
-func NewRepoRootMatcher(root AbsolutePath) (RepoRootMatcher, error) {
-	ps, err := ReadPatterns(root)
+func NewRepoRootMatcher(ctx context.Context, root AbsolutePath) (RepoRootMatcher, error) {
+	ps, err := ReadPatterns(ctx, root)
 	if err != nil {
 		return RepoRootMatcher{}, err
 	}
@@ -40,6 +43,29 @@ func (m *RepoRootMatcher) MatchFile(path AbsolutePath) bool {
 	return m.Match(parts, false)
 }
 
+// FSMatcher matches slash-separated paths relative to the root of a file system
+// against its gitignore patterns.
+type FSMatcher struct {
+	gitignore.Matcher
+}
+
+// NewFSMatcher reads the gitignore patterns of the file system fsys, whose root
+// is the repository root. See ReadPatternsFS.
+func NewFSMatcher(ctx context.Context, fsys fs.FS) (FSMatcher, error) {
+	ps, err := ReadPatternsFS(ctx, fsys)
+	if err != nil {
+		return FSMatcher{}, err
+	}
+
+	return FSMatcher{gitignore.NewMatcher(ps)}, nil
+}
+
+// MatchFile reports whether the file at the slash-separated path name,
+// relative to the root of the file system, is ignored.
+func (m FSMatcher) MatchFile(name string) bool {
+	return m.Match(strings.Split(name, "/"), false)
+}
+
 // ReadPatterns reads gitignore patterns from the repository, starting with
 // .git/info/exclude at the repository root, then recursively traversing the
 // directory structure to read all .gitignore and .tobiignore files.
@@ -47,36 +73,51 @@ func (m *RepoRootMatcher) MatchFile(path AbsolutePath) bool {
 // Patterns are returned in ascending order of priority (last higher), with
 // nested .gitignore and .tobiignore files overriding parent patterns. Nested .git folders
 // are not supported.
-func ReadPatterns(root AbsolutePath) ([]gitignore.Pattern, error) {
+//
+// The traversal stops with ctx.Err() as soon as ctx is done.
+func ReadPatterns(ctx context.Context, root AbsolutePath) ([]gitignore.Pattern, error) {
+	return readPatterns(ctx, os.DirFS(root.String()), splitPath(root.String()))
+}
+
+// ReadPatternsFS reads gitignore patterns from the file system fsys, like
+// ReadPatterns, with the root of fsys as the repository root. Patterns apply to
+// paths relative to that root, split into their slash-separated segments.
+func ReadPatternsFS(ctx context.Context, fsys fs.FS) ([]gitignore.Pattern, error) {
+	return readPatterns(ctx, fsys, nil)
+}
+
+// readPatterns reads gitignore patterns from fsys. The domain of every pattern,
+// i.e. the directory it applies to, is prefixed with base.
+func readPatterns(ctx context.Context, fsys fs.FS, base []string) ([]gitignore.Pattern, error) {
 	// load patterns from .git/info/exclude
 	// Errors are acceptable. We'll just start with a nil slice.
-	ps, _ := readIgnoreFile(root.join(infoExcludeFile))
+	ps, _ := readIgnoreFile(fsys, infoExcludeFile, base)
 
-	err := filepath.WalkDir(root.String(), func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
 		// Return out of WalkDir as soon as there's an error
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// Skip .git directory
-		if d.IsDir() {
+		if d.IsDir() && name != "." {
 			if d.Name() == gitDir {
-				return filepath.SkipDir
+				return fs.SkipDir
 			}
 
 			m := gitignore.NewMatcher(ps)
-			if m.Match(splitPath(path), true) {
-				return filepath.SkipDir
+			if m.Match(joinSegments(base, name), true) {
+				return fs.SkipDir
 			}
 		}
 
 		// files are walked in lexical order, so .tobiignore files override .gitignore files
 		// TODO: test this assumption
 		if d.Type().IsRegular() && (d.Name() == gitignoreFile || d.Name() == tobiignoreFile) {
-			// Since root is absolute when we pass it to WalkDir, path is absolute.
-			// It's safe to construct AbsolutePath directly from path.
-			p := NewAbsolutePathUnchecked(path)
-			subps, err := readIgnoreFile(p)
+			subps, err := readIgnoreFile(fsys, name, joinSegments(base, path.Dir(name)))
 			if err != nil {
 				return err
 			}
@@ -92,20 +133,11 @@ func ReadPatterns(root AbsolutePath) ([]gitignore.Pattern, error) {
 	return ps, nil
 }
 
-// readIgnoreFile reads and parses patterns from a gitignore file.
-// Skips comment lines (#) and empty lines. Handles .git/info/exclude files
-// by applying their patterns at the repository root level.
-func readIgnoreFile(ignoreFile AbsolutePath) ([]gitignore.Pattern, error) {
-	path := ignoreFile.String()
-	domain := splitPath(filepath.Dir(path))
-
-	if strings.HasSuffix(path, infoExcludeFile) {
-		// .git/info/exclude patterns apply to repository root, not .git/info directory
-		// so we move up 2 levels
-		domain = domain[:len(domain)-2]
-	}
-
-	f, err := os.Open(path)
+// readIgnoreFile reads and parses patterns from the gitignore file at name in
+// fsys. Skips comment lines (#) and empty lines. Patterns apply to the
+// directory whose path segments are domain.
+func readIgnoreFile(fsys fs.FS, name string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +159,15 @@ func readIgnoreFile(ignoreFile AbsolutePath) ([]gitignore.Pattern, error) {
 	return ps, nil
 }
 
+// joinSegments returns the segments of the slash-separated path name, relative
+// to the directory whose segments are base.
+func joinSegments(base []string, name string) []string {
+	if name == "." {
+		return base
+	}
+	return append(slices.Clip(base), strings.Split(name, "/")...)
+}
+
 type AbsolutePath struct {
 	path string
 }
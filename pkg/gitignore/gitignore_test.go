@@ -1,7 +1,11 @@
 package gitignore
 
 import (
+	"context"
+	"os"
+	"path"
 	"testing"
+	"testing/fstest"
 
 	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/stretchr/testify/require"
@@ -67,9 +71,8 @@ func Test_readIgnoreFile(t *testing.T) {
 		defer tt.dir.Remove()
 
 		t.Run(tt.name, func(_ *testing.T) {
-			path, err := NewAbsolutePath(tt.dir.Join(tt.relIgnoreFile))
-			r.NoError(err)
-			ps, err := readIgnoreFile(path)
+			domain := joinSegments(splitPath(tt.dir.Path()), path.Dir(tt.relIgnoreFile))
+			ps, err := readIgnoreFile(os.DirFS(tt.dir.Path()), tt.relIgnoreFile, domain)
 
 			r.NoError(err)
 			r.Len(ps, tt.patternCount)
@@ -228,7 +231,7 @@ func TestReadPatterns(t *testing.T) {
 			// Execute
 			root, err := NewAbsolutePath(tt.dir.Path())
 			r.NoError(err)
-			ps, err := ReadPatterns(root)
+			ps, err := ReadPatterns(context.Background(), root)
 
 			// Assert
 			r.NoError(err)
@@ -246,10 +249,45 @@ func TestReadPatterns(t *testing.T) {
 	}
 }
 
+func TestReadPatternsFS(t *testing.T) {
+	r := require.New(t)
+
+	fsys := fstest.MapFS{
+		".git/info/exclude":  {Data: []byte("*.tmp")},
+		".gitignore":         {Data: []byte("*.log\nprivate/")},
+		"level1/.tobiignore": {Data: []byte("draft.md")},
+		"level1/draft.md":    {},
+		"level1/note.md":     {},
+		"private/.gitignore": {Data: []byte("should-not-be-processed")},
+		"note.md":            {},
+	}
+
+	ps, err := ReadPatternsFS(context.Background(), fsys)
+	r.NoError(err)
+	r.Len(ps, 4)
+
+	m, err := NewFSMatcher(context.Background(), fsys)
+	r.NoError(err)
+
+	items := map[string]bool{
+		"note.md":         false,
+		"debug.log":       true,
+		"data.tmp":        true,
+		"level1/draft.md": true,
+		"level1/note.md":  false,
+		"draft.md":        false,
+		"private/note.md": true,
+	}
+	for f, want := range items {
+		r.Equal(want, m.MatchFile(f), f)
+	}
+}
+
 func TestReadPatterns_ErrorHandling(t *testing.T) {
 	testCases := []struct {
 		name        string
 		path        string
+		canceled    bool
 		expectedErr string
 	}{
 		{
@@ -257,16 +295,28 @@ func TestReadPatterns_ErrorHandling(t *testing.T) {
 			path:        "/nonexistent/directory",
 			expectedErr: "no such file or directory",
 		},
+		{
+			name:        "canceled context",
+			path:        t.TempDir(),
+			canceled:    true,
+			expectedErr: context.Canceled.Error(),
+		},
 	}
 
 	r := require.New(t)
 
 	for _, tt := range testCases {
 		t.Run(tt.name, func(_ *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
 			// Execute
 			root, err := NewAbsolutePath(tt.path)
 			r.NoError(err)
-			ps, err := ReadPatterns(root)
+			ps, err := ReadPatterns(ctx, root)
 
 			// Assert
 			r.Error(err)
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	set "github.com/deckarep/golang-set/v2"
@@ -34,7 +35,13 @@ func (tg *TagGlobs) Match(tag string) bool {
 //
 // Returns an error if the file cannot be read or any glob pattern fails to compile.
 func NewTagGlobs(path string) (TagGlobs, error) {
-	lines, err := readExcludePatterns(path)
+	return NewTagGlobsFS(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+// NewTagGlobsFS is like NewTagGlobs, but reads exclude patterns from the file
+// at name in the file system fsys.
+func NewTagGlobsFS(fsys fs.FS, name string) (TagGlobs, error) {
+	lines, err := readExcludePatterns(fsys, name)
 	if err != nil {
 		return TagGlobs{}, err
 	}
@@ -52,22 +59,22 @@ func NewTagGlobs(path string) (TagGlobs, error) {
 	return TagGlobs{Globs: globs}, nil
 }
 
-// readExcludePatterns reads lines from the specified file path.
+// readExcludePatterns reads lines from the file at name in fsys.
 // Lines starting with '#' are treated as comments and ignored.
 //
 // Returns a set of non-empty, non-comment, and deduplicated lines.
 //
 // Returns an empty set without error if the file doesn't exist or lacks read permissions.
-func readExcludePatterns(path string) (set.Set[string], error) {
+func readExcludePatterns(fsys fs.FS, name string) (set.Set[string], error) {
 	lines := set.NewSet[string]()
 
-	f, err := os.Open(path)
+	f, err := fsys.Open(name)
 	if err != nil {
 		switch {
 		case errors.Is(err, fs.ErrNotExist):
 			return lines, nil
 		case errors.Is(err, fs.ErrPermission):
-			log.Printf("permission denied to read %s", path)
+			log.Printf("permission denied to read %s", name)
 			return lines, nil
 		default:
 			return nil, err
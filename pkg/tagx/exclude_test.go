@@ -1,7 +1,9 @@
 package tagx
 
 import (
+	"os"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 	"gotest.tools/v3/fs"
@@ -69,9 +71,7 @@ func TestLoadPatterns(t *testing.T) {
 		defer dir.Remove()
 
 		t.Run(tt.name, func(_ *testing.T) {
-			root := dir.Join(".tobi.exclude")
-
-			actual, err := readExcludePatterns(root)
+			actual, err := readExcludePatterns(os.DirFS(dir.Path()), ".tobi.exclude")
 			r.NoError(err)
 
 			r.Equal(tt.want, set.Sorted(actual))
@@ -170,6 +170,25 @@ func TestTagGlobs(t *testing.T) {
 	}
 }
 
+func TestNewTagGlobsFS(t *testing.T) {
+	r := require.New(t)
+
+	fsys := fstest.MapFS{
+		"vault/.tobi.exclude": {Data: []byte("daily/*\nwork")},
+	}
+
+	tg, err := NewTagGlobsFS(fsys, "vault/.tobi.exclude")
+	r.NoError(err)
+	r.True(tg.Match("daily/2025"))
+	r.True(tg.Match("work"))
+	r.False(tg.Match("golang"))
+
+	// a missing file excludes nothing
+	tg, err = NewTagGlobsFS(fsys, ".tobi.exclude")
+	r.NoError(err)
+	r.Empty(tg.Globs)
+}
+
 func TestTagGlobs_ErrorCases(t *testing.T) {
 	testCases := []struct {
 		name        string
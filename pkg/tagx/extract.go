@@ -1,65 +1,151 @@
 package tagx
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/goccy/go-yaml"
-	"github.com/sourcegraph/conc/pool"
 )
 
+// Extract returns the tags of the note s, from its YAML frontmatter tag
+// properties followed by its body, in order of appearance.
+//
+// Returns an error if the frontmatter cannot be parsed.
 func Extract(s string) ([]string, error) {
-	marker := "---\n"
-
-	// add a newline handle notes with only frontmatter and the closing marker doesn't end with a newline.
-	// this ensures SplitN returns 3 parts instead of 2, so frontmatter gets parsed correctly
-	parts := strings.SplitN(s+"\n", marker, 3)
-
-	if len(parts) != 3 {
-		return fromBody(s)
-	}
-	if parts[0] != "" {
+	fm, body, ok := splitFrontmatter(s)
+	if !ok {
 		return fromBody(s)
 	}
 
-	p := pool.NewWithResults[[]string]().WithErrors().WithMaxGoroutines(2)
-	p.Go(func() ([]string, error) {
-		return fromFrontmatter(parts[1])
-	})
-	p.Go(func() ([]string, error) {
-		return fromBody(parts[2])
-	})
+	tags, err := fromFrontmatter(s[fm.start:fm.end])
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := p.Wait()
+	bodyTags, err := fromBody(s[body:])
 	if err != nil {
 		return nil, err
 	}
 
-	var tags []string
-	for _, r := range res {
-		tags = append(tags, r...)
+	return append(tags, bodyTags...), nil
+}
+
+// span is a range of byte offsets [start, end) in a note.
+type span struct {
+	start, end int
+}
+
+// splitFrontmatter locates the YAML frontmatter of the note s. The frontmatter
+// must start on the first line with a line consisting of "---", and ends with
+// the next such line, which may be the last line of the note without a
+// trailing newline. Lines may end with "\r\n".
+//
+// Returns the span of the frontmatter content, excluding the markers, and the
+// offset at which the body starts. ok is false if s has no frontmatter,
+// including when the opening marker is never closed, in which case the whole
+// note is body.
+func splitFrontmatter(s string) (fm span, body int, ok bool) {
+	first, _, found := strings.Cut(s, "\n")
+	if !found || strings.TrimSuffix(first, "\r") != "---" {
+		return span{}, 0, false
 	}
 
-	return tags, nil
+	start := len(first) + 1
+	for pos := start; pos <= len(s); {
+		end := strings.IndexByte(s[pos:], '\n')
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += pos
+		}
+
+		if strings.TrimSuffix(s[pos:end], "\r") == "---" {
+			return span{start, pos}, min(end+1, len(s)), true
+		}
+
+		pos = end + 1
+	}
+
+	return span{}, 0, false
 }
 
+// tagChars is the character class of a tag, following Obsidian's tag grammar:
+// any character except whitespace, ASCII punctuation other than '_', '-' and '/',
+// and the General Punctuation (U+2000-U+206F) and Supplemental Punctuation
+// (U+2E00-U+2E7F) blocks. Letters, numbers and marks of every script, as well as
+// emoji, are allowed. Zero-width joiners (U+200C, U+200D) are kept so that
+// joined emoji sequences and scripts that rely on them stay in one piece.
+const tagChars = `[^\s\p{Z}\x{FEFF}\x{2000}-\x{200B}\x{200E}-\x{206F}\x{2E00}-\x{2E7F}` +
+	"'!\"#$%&()*+,.:;<=>?@^`{|}~\\[\\]\\\\" + `]`
+
 var (
-	frontmatterTagRegex = regexp.MustCompile(`^#?([a-zA-Z0-9_/-]+)$`)
-	inlineTagRegex      = regexp.MustCompile(`(?:^|\s)#([A-Za-z0-9_/-]+)`)
+	frontmatterTagRegex = regexp.MustCompile(`^#?(` + tagChars + `+)$`)
+	inlineTagRegex      = regexp.MustCompile(`(?:^|[\s\p{Z}\x{FEFF}])#(` + tagChars + `+)`)
 	allNumericRegex     = regexp.MustCompile(`^[0-9]+$`)
 )
 
+// tagList is the value of a frontmatter tag property. It accepts every shape
+// Obsidian accepts: a list of tags, a single tag, or a string of comma- or
+// space-separated tags. Non-string scalars such as numbers are kept in their
+// textual form so they can be validated like any other tag.
+type tagList []string
+
+func (tl *tagList) UnmarshalYAML(unmarshal func(any) error) error {
+	var v any
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		*tl = nil
+	case []any:
+		tags := make([]string, 0, len(v))
+		for _, e := range v {
+			switch e := e.(type) {
+			case nil, []any, map[string]any:
+				// nested collections are not tags
+			case string:
+				tags = append(tags, e)
+			default:
+				tags = append(tags, fmt.Sprint(e))
+			}
+		}
+		*tl = tags
+	case string:
+		*tl = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	case map[string]any:
+		*tl = nil
+	default:
+		*tl = tagList{fmt.Sprint(v)}
+	}
+
+	return nil
+}
+
 func fromFrontmatter(s string) ([]string, error) {
+	// Obsidian reads the singular "tag" key as well as the capitalized keys used
+	// by older versions.
 	var fm struct {
-		Tags []string `yaml:"tags"`
+		Tags       tagList `yaml:"tags"`
+		Tag        tagList `yaml:"tag"`
+		LegacyTags tagList `yaml:"Tags"`
+		LegacyTag  tagList `yaml:"Tag"`
 	}
 
 	if err := yaml.Unmarshal([]byte(s), &fm); err != nil {
 		return nil, err
 	}
 
-	tags := make([]string, 0, len(fm.Tags))
-	for _, tag := range fm.Tags {
+	values := slices.Concat(fm.Tags, fm.Tag, fm.LegacyTags, fm.LegacyTag)
+
+	tags := make([]string, 0, len(values))
+	for _, tag := range values {
 		matches := frontmatterTagRegex.FindStringSubmatch(tag)
 		if len(matches) != 2 {
 			// log.Printf("invalid tag format in frontmatter: %s", tag)
@@ -76,8 +162,10 @@ func fromFrontmatter(s string) ([]string, error) {
 	return tags, nil
 }
 
+// fromBody extracts inline tags from the body of a note. Code blocks, inline code
+// spans and comments are skipped, the same way Obsidian skips them.
 func fromBody(s string) ([]string, error) {
-	matches := inlineTagRegex.FindAllStringSubmatch(s, -1)
+	matches := inlineTagRegex.FindAllStringSubmatch(mask(s), -1)
 
 	var tags []string
 	for _, m := range matches {
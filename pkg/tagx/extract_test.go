@@ -44,6 +44,21 @@ func Test_fromFrontmatter(t *testing.T) {
 			input: "tags:\n  - \"##cobra\"\n  - golang\n",
 			want:  []string{"golang"},
 		},
+		{
+			name:  "with non-ASCII scripts",
+			input: "tags:\n  - café\n  - 日本語/文法\n  - русский\n  - tiếng-việt\n  - 한국어",
+			want:  []string{"café", "日本語/文法", "русский", "tiếng-việt", "한국어"},
+		},
+		{
+			name:  "with emoji",
+			input: "tags:\n  - \"📚/reading\"\n  - \"#👨‍💻\"",
+			want:  []string{"📚/reading", "👨‍💻"},
+		},
+		{
+			name:  "skip tags with punctuation",
+			input: "tags:\n  - \"go lang\"\n  - \"go,lang\"\n  - \"go…lang\"\n  - golang",
+			want:  []string{"golang"},
+		},
 		{
 			name:  "empty tags array",
 			input: "tags: []",
@@ -70,9 +85,54 @@ func Test_fromFrontmatter(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "tags field with wrong type (string instead of array)",
-			input:   "tags: \"golang,cobra,cli\"",
-			wantErr: true,
+			name:  "comma-separated string",
+			input: "tags: \"golang,cobra, cli\"",
+			want:  []string{"golang", "cobra", "cli"},
+		},
+		{
+			name:  "space-separated string",
+			input: "tags: \"golang cobra  #cli\"",
+			want:  []string{"golang", "cobra", "cli"},
+		},
+		{
+			name:  "single scalar",
+			input: "tags: golang/cobra",
+			want:  []string{"golang/cobra"},
+		},
+		{
+			name:  "single numeric scalar",
+			input: "tags: 2024",
+			want:  []string{},
+		},
+		{
+			name:  "null value",
+			input: "tags:",
+			want:  []string{},
+		},
+		{
+			name:  "skip nested collections",
+			input: "tags:\n  - golang\n  - [cobra]\n  - {cli: true}\n  -\n",
+			want:  []string{"golang"},
+		},
+		{
+			name:  "mapping value",
+			input: "tags:\n  golang: true",
+			want:  []string{},
+		},
+		{
+			name:  "singular tag key",
+			input: "tag: golang",
+			want:  []string{"golang"},
+		},
+		{
+			name:  "legacy capitalized keys",
+			input: "Tags: [golang, cobra]\nTag: cli",
+			want:  []string{"golang", "cobra", "cli"},
+		},
+		{
+			name:  "tags and tag keys together",
+			input: "tags: [golang]\ntag: cobra",
+			want:  []string{"golang", "cobra"},
 		},
 	}
 
@@ -150,6 +210,61 @@ func Test_fromBody(t *testing.T) {
 			input: "## Header\n\nSome text about #golang and #cobra tools.\n\n- List item with #cli tag\n",
 			want:  []string{"golang", "cobra", "cli"},
 		},
+		{
+			name:  "with non-ASCII scripts",
+			input: "Notes on #café, #日本語/文法 and #русский; also #tiếng-việt and #한국어.",
+			want:  []string{"café", "日本語/文法", "русский", "tiếng-việt", "한국어"},
+		},
+		{
+			name:  "with combining marks",
+			input: "Decomposed #cafe\u0301 and #हिन्दी tags",
+			want:  []string{"cafe\u0301", "हिन्दी"},
+		},
+		{
+			name:  "with emoji",
+			input: "Currently #📚/reading and #👨‍💻 #✅done",
+			want:  []string{"📚/reading", "👨‍💻", "✅done"},
+		},
+		{
+			name:  "terminated by punctuation",
+			input: "(#golang) #cobra! #cli? #a:b #x.y #quote\" #em—dash #dots… #br[x]",
+			want:  []string{"cobra", "cli", "a", "x", "quote", "em", "dots", "br"},
+		},
+		{
+			name:  "preceded by unicode whitespace",
+			input: "日本語\u3000#タグ and\u00a0#nbsp",
+			want:  []string{"タグ", "nbsp"},
+		},
+		{
+			name:  "skip tags in fenced code blocks",
+			input: "#golang\n```cpp\n#include <iostream>\n```\n~~~css\na { color: #fff; }\n~~~\n#cobra",
+			want:  []string{"golang", "cobra"},
+		},
+		{
+			name:  "skip tags in unclosed fenced code block",
+			input: "#golang\n```\n#define X 1\n",
+			want:  []string{"golang"},
+		},
+		{
+			name:  "skip tags in indented code blocks",
+			input: "#golang\n\n    #pragma once\n\tint #x;\n\nback to #cli",
+			want:  []string{"golang", "cli"},
+		},
+		{
+			name:  "keep tags in nested list items",
+			input: "- parent #golang\n\n    - child #cobra",
+			want:  []string{"golang", "cobra"},
+		},
+		{
+			name:  "skip tags in inline code spans",
+			input: "Use `#define` and `` #pragma ` `` with #cli",
+			want:  []string{"cli"},
+		},
+		{
+			name:  "skip tags in comments",
+			input: "#golang %% #draft %% and <!-- #todo -->\n%%\n#hidden\n%%\n<!--\n#old\n-->\n#cobra",
+			want:  []string{"golang", "cobra"},
+		},
 	}
 
 	r := require.New(t)
@@ -164,6 +279,67 @@ func Test_fromBody(t *testing.T) {
 	}
 }
 
+func Test_splitFrontmatter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		fm     string
+		body   string
+		wantOk bool
+	}{
+		{
+			name:   "frontmatter and body",
+			input:  "---\ntags: [golang]\n---\nbody",
+			fm:     "tags: [golang]\n",
+			body:   "body",
+			wantOk: true,
+		},
+		{
+			name:   "closing marker on the last line",
+			input:  "---\ntags: [golang]\n---",
+			fm:     "tags: [golang]\n",
+			body:   "",
+			wantOk: true,
+		},
+		{
+			name:   "crlf line endings",
+			input:  "---\r\ntags: [golang]\r\n---\r\nbody",
+			fm:     "tags: [golang]\r\n",
+			body:   "body",
+			wantOk: true,
+		},
+		{
+			name:   "unclosed frontmatter",
+			input:  "---\ntags: [golang]\nbody",
+			wantOk: false,
+		},
+		{
+			name:   "opening marker only",
+			input:  "---\n",
+			wantOk: false,
+		},
+		{
+			name:   "no opening marker",
+			input:  "body\n---\ntags: [golang]\n---\n",
+			wantOk: false,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			fm, body, ok := splitFrontmatter(tt.input)
+			r.Equal(tt.wantOk, ok)
+			if !ok {
+				return
+			}
+			r.Equal(tt.fm, tt.input[fm.start:fm.end])
+			r.Equal(tt.body, tt.input[body:])
+		})
+	}
+}
+
 func Test_extract(t *testing.T) {
 	testCases := []struct {
 		name  string
@@ -207,6 +383,11 @@ func Test_extract(t *testing.T) {
 			input: "",
 			want:  nil,
 		},
+		{
+			name:  "legacy frontmatter with string tags",
+			input: "---\nTags: golang, cobra\n---\nContent with #cli tag.",
+			want:  []string{"golang", "cobra", "cli"},
+		},
 		{
 			name:  "frontmatter with no tags field",
 			input: "---\ntitle: My Note\nauthor: test\n---\nContent with #golang tag.",
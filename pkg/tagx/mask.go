@@ -0,0 +1,260 @@
+package tagx
+
+import (
+	"bytes"
+	"strings"
+)
+
+type commentKind int
+
+const (
+	noComment commentKind = iota
+	percentComment
+	htmlComment
+)
+
+var (
+	percentDelim = []byte("%%")
+	htmlOpen     = []byte("<!--")
+	htmlClose    = []byte("-->")
+)
+
+// fence describes an open fenced code block.
+type fence struct {
+	char byte
+	size int
+}
+
+// mask returns a copy of s in which every region that Obsidian does not scan for
+// tags is replaced with spaces: fenced code blocks (``` and ~~~), indented code
+// blocks, inline code spans, Obsidian %% comments and HTML <!-- --> comments.
+//
+// The result has the same length as s and keeps its line breaks, so byte offsets
+// into the masked string are valid offsets into s.
+//
+// Inline code spans are only recognized within a single line. Comments may span
+// multiple lines and, like unclosed fenced blocks, extend to the end of the input
+// when they are never closed.
+func mask(s string) string {
+	b := []byte(s)
+
+	var (
+		open      *fence
+		comment   = noComment
+		indented  bool
+		inList    bool
+		prevBlank = true
+	)
+
+	for start := 0; start < len(b); {
+		end := bytes.IndexByte(b[start:], '\n')
+		if end < 0 {
+			end = len(b)
+		} else {
+			end += start
+		}
+		line := b[start:end]
+		blank := isBlank(line)
+
+		switch {
+		case open != nil:
+			if closesFence(line, *open) {
+				open = nil
+			}
+			blankOut(line)
+		case comment != noComment:
+			comment = maskInline(line, comment)
+		case openFence(line) != nil:
+			open = openFence(line)
+			blankOut(line)
+		case !blank && indentWidth(line) >= 4 && (indented || (prevBlank && !inList)):
+			indented = true
+			blankOut(line)
+		default:
+			if !blank {
+				indented = false
+				if indentWidth(line) == 0 {
+					inList = isListItem(line)
+				} else if isListItem(line) {
+					inList = true
+				}
+			}
+			comment = maskInline(line, comment)
+		}
+
+		prevBlank = blank
+		start = end + 1
+	}
+
+	return string(b)
+}
+
+// maskInline blanks out inline code spans and comments within a single line.
+// The comment argument is the comment state carried over from the previous line,
+// and the returned value is the state to carry over to the next one.
+func maskInline(line []byte, comment commentKind) commentKind {
+	for i := 0; i < len(line); {
+		switch comment {
+		case percentComment, htmlComment:
+			closing := percentDelim
+			if comment == htmlComment {
+				closing = htmlClose
+			}
+			j := bytes.Index(line[i:], closing)
+			if j < 0 {
+				blankOut(line[i:])
+				return comment
+			}
+			j = i + j + len(closing)
+			blankOut(line[i:j])
+			comment = noComment
+			i = j
+			continue
+		}
+
+		switch {
+		case line[i] == '`':
+			n := runLength(line[i:], '`')
+			j := closingCodeSpan(line, i+n, n)
+			if j < 0 {
+				// an unmatched backtick run is literal text
+				i += n
+				continue
+			}
+			blankOut(line[i:j])
+			i = j
+		case bytes.HasPrefix(line[i:], percentDelim):
+			blankOut(line[i : i+len(percentDelim)])
+			comment = percentComment
+			i += len(percentDelim)
+		case bytes.HasPrefix(line[i:], htmlOpen):
+			blankOut(line[i : i+len(htmlOpen)])
+			comment = htmlComment
+			i += len(htmlOpen)
+		default:
+			i++
+		}
+	}
+
+	return comment
+}
+
+// closingCodeSpan returns the offset just past the backtick run of exactly n
+// backticks that closes a code span opened before from, or -1 if there is none.
+func closingCodeSpan(line []byte, from, n int) int {
+	for i := from; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		m := runLength(line[i:], '`')
+		if m == n {
+			return i + m
+		}
+		i += m
+	}
+	return -1
+}
+
+// openFence reports the fence opened by line, or nil if line does not open a
+// fenced code block. A fence is a run of at least three backticks or tildes,
+// indented by at most three spaces, optionally followed by an info string.
+func openFence(line []byte) *fence {
+	rest, ok := trimFenceIndent(line)
+	if !ok || len(rest) == 0 || (rest[0] != '`' && rest[0] != '~') {
+		return nil
+	}
+
+	c := rest[0]
+	n := runLength(rest, c)
+	if n < 3 {
+		return nil
+	}
+
+	// backtick fences cannot have backticks in their info string
+	if c == '`' && bytes.IndexByte(rest[n:], '`') >= 0 {
+		return nil
+	}
+
+	return &fence{char: c, size: n}
+}
+
+// closesFence reports whether line closes the fenced code block f: a run of the
+// same fence character at least as long as the opening one, followed only by
+// whitespace.
+func closesFence(line []byte, f fence) bool {
+	rest, ok := trimFenceIndent(line)
+	if !ok {
+		return false
+	}
+	n := runLength(rest, f.char)
+	return n >= f.size && isBlank(rest[n:])
+}
+
+func trimFenceIndent(line []byte) ([]byte, bool) {
+	i := 0
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return line[i:], i <= 3
+}
+
+// indentWidth returns the width of the leading whitespace of line, counting a
+// tab as four columns.
+func indentWidth(line []byte) int {
+	w := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			w++
+		case '\t':
+			w += 4
+		default:
+			return w
+		}
+	}
+	return w
+}
+
+// isListItem reports whether line starts a bullet or ordered list item.
+// Indented lines under a list item are list content, not indented code.
+func isListItem(line []byte) bool {
+	s := strings.TrimLeft(string(line), " \t")
+	if s == "" {
+		return false
+	}
+
+	switch s[0] {
+	case '-', '*', '+':
+		return len(s) == 1 || s[1] == ' ' || s[1] == '\t'
+	}
+
+	i := 0
+	for i < len(s) && i < 9 && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(s) || (s[i] != '.' && s[i] != ')') {
+		return false
+	}
+	return i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\t'
+}
+
+func runLength(b []byte, c byte) int {
+	n := 0
+	for n < len(b) && b[n] == c {
+		n++
+	}
+	return n
+}
+
+func isBlank(line []byte) bool {
+	return len(bytes.TrimSpace(line)) == 0
+}
+
+func blankOut(b []byte) {
+	for i := range b {
+		if b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tagx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mask(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text is unchanged",
+			input: "some #golang text\nanother line",
+			want:  "some #golang text\nanother line",
+		},
+		{
+			name:  "backtick fence with info string",
+			input: "a\n```go\nx := 1\n```\nb",
+			want:  "a\n     \n      \n   \nb",
+		},
+		{
+			name:  "tilde fence closed by longer fence",
+			input: "~~~\n#x\n~~~~\nb",
+			want:  "   \n  \n    \nb",
+		},
+		{
+			name:  "backtick fence is not closed by tildes",
+			input: "```\n~~~\n#x",
+			want:  "   \n   \n  ",
+		},
+		{
+			name:  "inline code span",
+			input: "a `#x` b",
+			want:  "a      b",
+		},
+		{
+			name:  "double backtick code span",
+			input: "a ``#x ` y`` b",
+			want:  "a            b",
+		},
+		{
+			name:  "unmatched backtick is literal",
+			input: "a ` #x",
+			want:  "a ` #x",
+		},
+		{
+			name:  "percent comment",
+			input: "a %%#x%% b",
+			want:  "a        b",
+		},
+		{
+			name:  "multi-line html comment",
+			input: "a <!-- #x\n#y --> b",
+			want:  "a        \n       b",
+		},
+		{
+			name:  "comment markers inside code are literal",
+			input: "`%%` #x",
+			want:  "     #x",
+		},
+		{
+			name:  "indented code after blank line",
+			input: "a\n\n    #x\nb",
+			want:  "a\n\n      \nb",
+		},
+		{
+			name:  "indented line continuing a paragraph",
+			input: "a\n    #x",
+			want:  "a\n    #x",
+		},
+		{
+			name:  "multibyte text keeps its length",
+			input: "`日本` #語",
+			want:  "         #語",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got := mask(tt.input)
+
+			r.Len(got, len(tt.input))
+			r.Equal(tt.want, got)
+		})
+	}
+}
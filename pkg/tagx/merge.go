@@ -0,0 +1,168 @@
+package tagx
+
+import (
+	"slices"
+	"strings"
+)
+
+// Merger returns a RenameFunc that renames every tag in sources to target.
+func Merger(target string, sources ...string) RenameFunc {
+	return func(tag string) (string, bool) {
+		if slices.Contains(sources, tag) {
+			return target, true
+		}
+		return "", false
+	}
+}
+
+// Merge renames every tag in sources to target in the note s, like Rewrite.
+// Because a note carrying several of these tags ends up with target more than
+// once, the repeats of target in the frontmatter tag properties are then
+// removed, keeping the first occurrence.
+//
+// Returns the rewritten note and the number of renamed tag occurrences.
+func Merge(s, target string, sources []string) (string, int, error) {
+	out, n, err := Rewrite(s, Merger(target, sources...))
+	if err != nil || n == 0 {
+		return out, n, err
+	}
+
+	fm, _, ok := splitFrontmatter(out)
+	if !ok {
+		return out, n, nil
+	}
+
+	return out[:fm.start] + dedupeFrontmatter(out[fm.start:fm.end], target) + out[fm.end:], n, nil
+}
+
+// dedupeFrontmatter removes the repeats of tag from the values of the
+// frontmatter tag properties. Block sequence items are removed along with their
+// line, keeping its YAML comment, if any, on a line of its own. Flow sequence
+// items are removed along with their separating comma, and tags of delimited
+// strings along with their delimiter.
+//
+// De-duplication is best effort: if the tags extracted from the result are not
+// the original ones with tag left exactly once, fm is returned unchanged.
+func dedupeFrontmatter(fm, tag string) string {
+	tags, err := fromFrontmatter(fm)
+	if err != nil || countOf(tags, tag) < 2 {
+		return fm
+	}
+
+	var b strings.Builder
+	seen := false
+	for l := range valueLines(fm) {
+		if !l.ok {
+			b.WriteString(l.text)
+			continue
+		}
+
+		v := l.text[l.start:l.end]
+		var removed []span
+		switch l.kind {
+		case scalarValue:
+			matches := tagTokenRegex.FindAllStringSubmatchIndex(v, -1)
+			for i, m := range matches {
+				if v[m[2]:m[3]] != tag {
+					continue
+				}
+				if !seen {
+					seen = true
+					continue
+				}
+
+				// remove the tag with the delimiter after it, or before it if
+				// it is the last tag of the value
+				switch {
+				case i < len(matches)-1:
+					removed = append(removed, span{m[0], matches[i+1][0]})
+				case i > 0:
+					removed = append(removed, span{matches[i-1][1], m[1]})
+				}
+			}
+		case itemValue:
+			if !isItem(v, tag) {
+				break
+			}
+			if !seen {
+				seen = true
+				break
+			}
+
+			// keep the comment of the item, indented like the item
+			if comment := strings.TrimRight(l.text[l.end:], "\r\n"); comment != "" {
+				indent := l.text[:strings.IndexByte(l.text, '-')]
+				b.WriteString(indent + comment + l.text[len(strings.TrimRight(l.text, "\r\n")):])
+			}
+			continue
+		case flowValue:
+			items := splitItems(v, flowValue)
+			for i, it := range items {
+				if !isItem(v[it.start:it.end], tag) {
+					continue
+				}
+				if !seen {
+					seen = true
+					continue
+				}
+
+				// remove the item with the comma before it, or after it if it
+				// is the first item of the line
+				const wrapping = " \t\r\n[]"
+				item := v[it.start:it.end]
+				switch {
+				case i > 0:
+					end := it.start + len(strings.TrimRight(item, wrapping))
+					removed = append(removed, span{it.start - 1, end})
+				case i < len(items)-1:
+					start := it.end - len(strings.TrimLeft(item, wrapping))
+					next := v[items[i+1].start:]
+					end := items[i+1].start + len(next) - len(strings.TrimLeft(next, " \t"))
+					removed = append(removed, span{start, end})
+				}
+			}
+		}
+
+		b.WriteString(l.text[:l.start])
+		last := 0
+		for _, r := range removed {
+			r.start = max(r.start, last)
+			b.WriteString(v[last:r.start])
+			last = r.end
+		}
+		b.WriteString(v[last:])
+		b.WriteString(l.text[l.end:])
+	}
+
+	out := b.String()
+	// the properties are read in a fixed order rather than in document order,
+	// so only check that tag is left once and that other tags are unchanged
+	got, err := fromFrontmatter(out)
+	if err != nil || countOf(got, tag) != 1 || !slices.Equal(without(tags, tag), without(got, tag)) {
+		return fm
+	}
+
+	return out
+}
+
+// isItem reports whether the sequence item consists only of tag, optionally
+// prefixed with '#' and surrounded by whitespace, quotes and flow sequence
+// brackets.
+func isItem(item, tag string) bool {
+	matches := tagTokenRegex.FindAllStringSubmatchIndex(item, -1)
+	return len(matches) == 1 && isBareItem(item, matches[0]) && item[matches[0][2]:matches[0][3]] == tag
+}
+
+func countOf(tags []string, tag string) int {
+	n := 0
+	for _, t := range tags {
+		if t == tag {
+			n++
+		}
+	}
+	return n
+}
+
+func without(tags []string, tag string) []string {
+	return slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return t == tag })
+}
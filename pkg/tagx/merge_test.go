@@ -0,0 +1,101 @@
+package tagx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		want      string
+		wantCount int
+	}{
+		{
+			name:      "inline tags",
+			input:     "#ml and #ML, not #mlops or #ml/basics",
+			want:      "#machine-learning and #machine-learning, not #mlops or #ml/basics",
+			wantCount: 2,
+		},
+		{
+			name:      "block sequence",
+			input:     "---\ntags:\n  - machine-learning\n  - ml # short\n  - python\n  - ML\n---\n#ml",
+			want:      "---\ntags:\n  - machine-learning\n  # short\n  - python\n---\n#machine-learning",
+			wantCount: 3,
+		},
+		{
+			name:      "keep the comment of a duplicate item",
+			input:     "---\ntags:\n  - ml\n  - machine-learning # c\n---\n",
+			want:      "---\ntags:\n  - machine-learning\n  # c\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "flow sequence",
+			input:     "---\ntags: [ml, python, \"#ML\", machine-learning]\n---\n",
+			want:      "---\ntags: [machine-learning, python]\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "flow sequence starting with a duplicate",
+			input:     "---\ntag: ml\ntags: [ML, python]\n---\n",
+			want:      "---\ntag: machine-learning\ntags: [python]\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "only duplicates in a flow sequence",
+			input:     "---\ntags: [ml, ML]\n---\n",
+			want:      "---\ntags: [machine-learning]\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "preserve CRLF line endings",
+			input:     "---\r\ntags:\r\n  - ml\r\n  - ML\r\n---\r\n",
+			want:      "---\r\ntags:\r\n  - machine-learning\r\n---\r\n",
+			wantCount: 2,
+		},
+		{
+			name:      "delimited string",
+			input:     "---\ntags: ml, ML other\n---\n",
+			want:      "---\ntags: machine-learning, other\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "delimited string ending with a duplicate",
+			input:     "---\ntags: ml other ML # comment\n---\n",
+			want:      "---\ntags: machine-learning other # comment\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "delimited string starting with a duplicate",
+			input:     "---\ntag: ml\ntags: ML, other\n---\n",
+			want:      "---\ntag: machine-learning\ntags: other\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "keep duplicates that cannot be removed safely",
+			input:     "---\ntags: [ml,\n  ML]\n---\n",
+			want:      "---\ntags: [machine-learning,\n  machine-learning]\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "keep existing duplicates when nothing is merged",
+			input:     "---\ntags: [machine-learning, machine-learning]\n---\n",
+			want:      "---\ntags: [machine-learning, machine-learning]\n---\n",
+			wantCount: 0,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got, n, err := Merge(tt.input, "machine-learning", []string{"ml", "ML"})
+
+			r.NoError(err)
+			r.Equal(tt.want, got)
+			r.Equal(tt.wantCount, n)
+		})
+	}
+}
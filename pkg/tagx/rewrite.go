@@ -0,0 +1,319 @@
+package tagx
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RenameFunc maps a tag to its new name. It returns false to leave the tag
+// unchanged.
+type RenameFunc func(tag string) (string, bool)
+
+// Renamer returns a RenameFunc that renames the tag from to the tag to. If
+// descendants is true, tags nested under from are moved under to as well, e.g.
+// renaming "proj" to "project" turns "proj/alpha" into "project/alpha".
+func Renamer(from, to string, descendants bool) RenameFunc {
+	return func(tag string) (string, bool) {
+		switch {
+		case tag == from:
+			return to, true
+		case descendants && IsDescendant(tag, from):
+			return to + tag[len(from):], true
+		default:
+			return "", false
+		}
+	}
+}
+
+// IsValid reports whether tag is a valid tag name, without a leading '#'.
+func IsValid(tag string) bool {
+	return frontmatterTagRegex.MatchString(tag) &&
+		!strings.HasPrefix(tag, "#") &&
+		!allNumericRegex.MatchString(tag)
+}
+
+// Rewrite renames the tags of the note s, both in its frontmatter tag properties
+// and inline in its body. Everything else, including the formatting of the
+// frontmatter, code blocks, comments and line endings, is left untouched.
+//
+// Returns the rewritten note and the number of renamed tag occurrences.
+//
+// Returns an error if the frontmatter cannot be parsed, or if it cannot be
+// rewritten safely, that is if the tags extracted from the rewritten
+// frontmatter are not exactly the renamed tags of the original one.
+func Rewrite(s string, rename RenameFunc) (string, int, error) {
+	fm, body, ok := splitFrontmatter(s)
+	if !ok {
+		out, n := rewriteBody(s, rename)
+		return out, n, nil
+	}
+
+	newFM, fmCount, err := rewriteFrontmatter(s[fm.start:fm.end], rename)
+	if err != nil {
+		return "", 0, err
+	}
+	newBody, bodyCount := rewriteBody(s[body:], rename)
+
+	out := s[:fm.start] + newFM + s[fm.end:body] + newBody
+	return out, fmCount + bodyCount, nil
+}
+
+// rewriteBody renames inline tags in the body of a note. Tags inside code and
+// comments are skipped, like in fromBody.
+func rewriteBody(s string, rename RenameFunc) (string, int) {
+	matches := inlineTagRegex.FindAllStringSubmatchIndex(mask(s), -1)
+
+	var b strings.Builder
+	last, n := 0, 0
+	for _, m := range matches {
+		// m[2] and m[3] are the bounds of the tag, without the '#'
+		start, end := m[2], m[3]
+		tag := s[start:end]
+		if allNumericRegex.MatchString(tag) {
+			continue
+		}
+
+		renamed, ok := rename(tag)
+		if !ok || renamed == tag {
+			continue
+		}
+
+		b.WriteString(s[last:start])
+		b.WriteString(renamed)
+		last = end
+		n++
+	}
+
+	if n == 0 {
+		return s, 0
+	}
+
+	b.WriteString(s[last:])
+	return b.String(), n
+}
+
+var (
+	// tagKeyRegex matches a top-level frontmatter tag property
+	tagKeyRegex = regexp.MustCompile(`^(?:tags|tag|Tags|Tag)[ \t]*:`)
+	// sequenceItemRegex matches the indicator of a block sequence item
+	sequenceItemRegex = regexp.MustCompile(`^[ \t]*-(?:[ \t]|$)`)
+	// tagTokenRegex matches a tag candidate inside a frontmatter value
+	tagTokenRegex = regexp.MustCompile(`#?(` + tagChars + `+)`)
+)
+
+// valueKind is the shape of a frontmatter value, which determines how it is
+// split into tags.
+type valueKind int
+
+const (
+	// scalarValue is a string of comma- or space-separated tags
+	scalarValue valueKind = iota
+	// flowValue is part of a flow sequence, whose items are separated by commas
+	flowValue
+	// itemValue is a single block sequence item
+	itemValue
+)
+
+// valueLine is a line of frontmatter, along with the bounds of the tag property
+// value it holds, if any.
+type valueLine struct {
+	text string
+	// start and end are the bounds of the value in text, excluding any YAML
+	// comment. They are only set if ok is true.
+	start, end int
+	kind       valueKind
+	// ok is true if the line holds part of the value of a tag property.
+	ok bool
+}
+
+// valueLines returns an iterator over the lines of the frontmatter fm, locating
+// the values of the tag properties, whatever their shape: a block or flow
+// sequence, a single tag, or a delimited string.
+func valueLines(fm string) iter.Seq[valueLine] {
+	return func(yield func(valueLine) bool) {
+		inValue, inFlow := false, false
+
+		for line := range strings.Lines(fm) {
+			l := valueLine{text: line, ok: true}
+
+			switch {
+			case tagKeyRegex.MatchString(line):
+				inValue = true
+				l.start = strings.IndexByte(line, ':') + 1
+				inFlow = strings.HasPrefix(strings.TrimSpace(line[l.start:]), "[")
+			case inValue && strings.TrimSpace(line) == "":
+				l.ok = false
+			case inValue && (line[0] == ' ' || line[0] == '\t' || line[0] == ']' || sequenceItemRegex.MatchString(line)):
+				if loc := sequenceItemRegex.FindStringIndex(line); loc != nil && !inFlow {
+					l.start, l.kind = loc[1], itemValue
+				}
+			default:
+				inValue, inFlow = false, false
+				l.ok = false
+			}
+
+			if l.ok {
+				if inFlow {
+					l.kind = flowValue
+				}
+				l.end = l.start + commentStart(line[l.start:])
+				if inFlow && strings.Contains(line[l.start:l.end], "]") {
+					inFlow = false
+				}
+			}
+
+			if !yield(l) {
+				return
+			}
+		}
+	}
+}
+
+// rewriteFrontmatter renames tags in the values of the frontmatter tag
+// properties. The values are rewritten in place, token by token, so that quotes,
+// comments and the layout of lists are preserved.
+//
+// The result is verified by extracting the tags of the rewritten frontmatter.
+func rewriteFrontmatter(fm string, rename RenameFunc) (string, int, error) {
+	want, err := fromFrontmatter(fm)
+	if err != nil {
+		return "", 0, err
+	}
+	for i, t := range want {
+		if renamed, ok := rename(t); ok {
+			want[i] = renamed
+		}
+	}
+
+	var b strings.Builder
+	n := 0
+
+	for l := range valueLines(fm) {
+		if !l.ok {
+			b.WriteString(l.text)
+			continue
+		}
+
+		v := l.text[l.start:]
+		out, c := rewriteValue(v, l.end-l.start, l.kind, rename)
+		b.WriteString(l.text[:l.start])
+		b.WriteString(out)
+		n += c
+	}
+
+	out := b.String()
+	if n == 0 {
+		return fm, 0, nil
+	}
+
+	got, err := fromFrontmatter(out)
+	if err != nil || !slices.Equal(want, got) {
+		return "", 0, fmt.Errorf("cannot safely rewrite frontmatter tags %v", want)
+	}
+
+	return out, n, nil
+}
+
+// rewriteValue renames the tags of the frontmatter value v, up to the start of a
+// YAML comment at end. In scalar values, every token is a tag. In sequences, an
+// item is renamed only if it consists of a single tag, optionally quoted.
+func rewriteValue(v string, end int, kind valueKind, rename RenameFunc) (string, int) {
+	var b strings.Builder
+	last, n := 0, 0
+
+	for _, item := range splitItems(v[:end], kind) {
+		matches := tagTokenRegex.FindAllStringSubmatchIndex(v[item.start:item.end], -1)
+		if kind != scalarValue && (len(matches) != 1 || !isBareItem(v[item.start:item.end], matches[0])) {
+			continue
+		}
+
+		for _, m := range matches {
+			start, stop := item.start+m[2], item.start+m[3]
+			// tokens followed by ':' are mapping keys
+			if stop < end && v[stop] == ':' {
+				continue
+			}
+
+			tag := v[start:stop]
+			renamed, ok := rename(tag)
+			if !ok || renamed == tag {
+				continue
+			}
+
+			b.WriteString(v[last:start])
+			b.WriteString(renamed)
+			last = stop
+			n++
+		}
+	}
+
+	if n == 0 {
+		return v, 0
+	}
+
+	b.WriteString(v[last:])
+	return b.String(), n
+}
+
+// splitItems splits a frontmatter value into the spans of its sequence items.
+// Flow sequences are split on commas outside of quotes, while scalar values and
+// block sequence items are a single span.
+func splitItems(v string, kind valueKind) []span {
+	if kind != flowValue {
+		return []span{{0, len(v)}}
+	}
+
+	var items []span
+	var quote byte
+	start := 0
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, span{start, i})
+			start = i + 1
+		}
+	}
+	return append(items, span{start, len(v)})
+}
+
+// isBareItem reports whether the sequence item consists only of the token
+// matched by m, surrounded by whitespace, quotes and flow sequence brackets.
+func isBareItem(item string, m []int) bool {
+	const wrapping = " \t\r\n\"'[]"
+	return strings.Trim(item[:m[0]], wrapping) == "" && strings.Trim(item[m[1]:], wrapping) == ""
+}
+
+// commentStart returns the offset of the YAML comment in the value v, or
+// len(v) if there is none. A comment starts with a '#' outside of quotes that
+// is at the start of v or preceded by whitespace.
+func commentStart(v string) int {
+	var quote byte
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || v[i-1] == ' ' || v[i-1] == '\t'):
+			return i
+		}
+	}
+	return len(v)
+}
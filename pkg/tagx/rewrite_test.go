@@ -0,0 +1,207 @@
+package tagx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrite(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		from, to    string
+		descendants bool
+		want        string
+		wantCount   int
+	}{
+		{
+			name:      "inline tags",
+			input:     "About #golang and #golang/cobra, not #golangci.",
+			from:      "golang",
+			to:        "go",
+			want:      "About #go and #golang/cobra, not #golangci.",
+			wantCount: 1,
+		},
+		{
+			name:        "inline tags with descendants",
+			input:       "About #golang and #golang/cobra, not #golangci.",
+			from:        "golang",
+			to:          "lang/go",
+			descendants: true,
+			want:        "About #lang/go and #lang/go/cobra, not #golangci.",
+			wantCount:   2,
+		},
+		{
+			name:      "skip code and comments",
+			input:     "#golang\n```\n#golang\n```\n`#golang` %% #golang %%",
+			from:      "golang",
+			to:        "go",
+			want:      "#go\n```\n#golang\n```\n`#golang` %% #golang %%",
+			wantCount: 1,
+		},
+		{
+			name:      "block sequence",
+			input:     "---\ntitle: golang\ntags:\n  - golang # primary\n  - cobra\n  - \"golang/cli\"\n---\n#golang",
+			from:      "golang",
+			to:        "go",
+			want:      "---\ntitle: golang\ntags:\n  - go # primary\n  - cobra\n  - \"golang/cli\"\n---\n#go",
+			wantCount: 2,
+		},
+		{
+			name:      "block sequence without indentation",
+			input:     "---\ntags:\n- golang\n- \"#cobra\"\nauthor: golang\n---\n",
+			from:      "cobra",
+			to:        "golang/cobra",
+			want:      "---\ntags:\n- golang\n- \"#golang/cobra\"\nauthor: golang\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "flow sequence with quotes",
+			input:     "---\ntags: [\"#golang\", 'cobra', golang/cli]\naliases: [golang]\n---\n",
+			from:      "golang",
+			to:        "go",
+			want:      "---\ntags: [\"#go\", 'cobra', golang/cli]\naliases: [golang]\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "multi-line flow sequence",
+			input:     "---\ntags: [cobra,\n  golang]\n---\n",
+			from:      "golang",
+			to:        "go",
+			want:      "---\ntags: [cobra,\n  go]\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "delimited string",
+			input:     "---\nTags: golang, cobra golang\n---\n",
+			from:      "golang",
+			to:        "go",
+			want:      "---\nTags: go, cobra go\n---\n",
+			wantCount: 2,
+		},
+		{
+			name:      "singular key",
+			input:     "---\ntag: \"golang\"\n---\n",
+			from:      "golang",
+			to:        "go",
+			want:      "---\ntag: \"go\"\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "keep mapping keys",
+			input:     "---\ntags:\n  golang: true\n---\n",
+			from:      "golang",
+			to:        "go",
+			want:      "---\ntags:\n  golang: true\n---\n",
+			wantCount: 0,
+		},
+		{
+			name:      "preserve CRLF line endings",
+			input:     "---\r\ntags:\r\n  - golang\r\n---\r\nSome #golang\r\n",
+			from:      "golang",
+			to:        "go",
+			want:      "---\r\ntags:\r\n  - go\r\n---\r\nSome #go\r\n",
+			wantCount: 2,
+		},
+		{
+			name:      "non-ASCII tags",
+			input:     "---\ntags: [日本語]\n---\n#日本語/文法",
+			from:      "日本語",
+			to:        "nihongo",
+			want:      "---\ntags: [nihongo]\n---\n#日本語/文法",
+			wantCount: 1,
+		},
+		{
+			name:      "skip sequence items that are not tags",
+			input:     "---\ntags:\n  - go lang\n  - lang\nother: [lang, go lang]\n---\n",
+			from:      "lang",
+			to:        "language",
+			want:      "---\ntags:\n  - go lang\n  - language\nother: [lang, go lang]\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "skip flow sequence items that are not tags",
+			input:     "---\ntags: [go lang, \"lang\", 'a, lang']\n---\n",
+			from:      "lang",
+			to:        "language",
+			want:      "---\ntags: [go lang, \"language\", 'a, lang']\n---\n",
+			wantCount: 1,
+		},
+		{
+			name:      "no matching tag",
+			input:     "---\ntags: [cobra]\n---\n#cli",
+			from:      "golang",
+			to:        "go",
+			want:      "---\ntags: [cobra]\n---\n#cli",
+			wantCount: 0,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got, n, err := Rewrite(tt.input, Renamer(tt.from, tt.to, tt.descendants))
+
+			r.NoError(err)
+			r.Equal(tt.want, got)
+			r.Equal(tt.wantCount, n)
+		})
+	}
+}
+
+func TestRewrite_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		from, to string
+	}{
+		{
+			name:  "invalid YAML",
+			input: "---\ntags: [golang\n---\n",
+			from:  "golang",
+			to:    "go",
+		},
+		{
+			// an unquoted null is not a string once rewritten
+			name:  "rewritten value changes type",
+			input: "---\ntags:\n  - golang\n---\n",
+			from:  "golang",
+			to:    "null",
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			_, _, err := Rewrite(tt.input, Renamer(tt.from, tt.to, false))
+			r.Error(err)
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	testCases := []struct {
+		tag  string
+		want bool
+	}{
+		{tag: "golang", want: true},
+		{tag: "golang/cobra", want: true},
+		{tag: "日本語", want: true},
+		{tag: "y1984", want: true},
+		{tag: "1984", want: false},
+		{tag: "#golang", want: false},
+		{tag: "go lang", want: false},
+		{tag: "", want: false},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.tag, func(_ *testing.T) {
+			r.Equal(tt.want, IsValid(tt.tag))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package tagx
+
+import (
+	"slices"
+	"strings"
+)
+
+// TagNode is a node in the tag hierarchy formed by nesting tags with '/'.
+type TagNode struct {
+	// Name is the last segment of the tag, e.g. "Command" for "golang/cobra/Command".
+	Name string `json:"name"`
+	// Tag is the full tag, e.g. "golang/cobra/Command".
+	Tag string `json:"tag"`
+	// Count is the number of usages of exactly this tag.
+	Count int `json:"count"`
+	// Total is the number of usages of this tag and all of its descendants.
+	Total int `json:"total"`
+	// Children are sorted by descending total, then by name.
+	Children []*TagNode `json:"children,omitempty"`
+}
+
+// NewTagTree builds the tag hierarchy from a map of tag counts. The returned root
+// node has an empty name and tag, and its total is the sum of all counts.
+//
+// Intermediate tags that are never used on their own, such as "golang" when only
+// "golang/cobra" is used, are included with a count of zero.
+func NewTagTree(counts map[string]int) *TagNode {
+	root := &TagNode{}
+
+	// nodes indexes every node by its full tag
+	nodes := map[string]*TagNode{}
+
+	for tag, c := range counts {
+		root.Total += c
+
+		parent := root
+		segs := strings.Split(tag, "/")
+		for i, seg := range segs {
+			prefix := strings.Join(segs[:i+1], "/")
+			n, ok := nodes[prefix]
+			if !ok {
+				n = &TagNode{Name: seg, Tag: prefix}
+				nodes[prefix] = n
+				parent.Children = append(parent.Children, n)
+			}
+			n.Total += c
+			parent = n
+		}
+		parent.Count += c
+	}
+
+	root.sort()
+	return root
+}
+
+func (n *TagNode) sort() {
+	slices.SortFunc(n.Children, func(a, b *TagNode) int {
+		if c := b.Total - a.Total; c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+// IsDescendant reports whether tag is nested under ancestor, e.g. "golang/cobra"
+// and "golang/cobra/Command" are descendants of "golang". A tag is not a
+// descendant of itself.
+func IsDescendant(tag, ancestor string) bool {
+	return len(tag) > len(ancestor) && tag[len(ancestor)] == '/' && strings.HasPrefix(tag, ancestor)
+}
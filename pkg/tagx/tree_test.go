@@ -0,0 +1,103 @@
+package tagx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTagTree(t *testing.T) {
+	testCases := []struct {
+		name   string
+		counts map[string]int
+		want   *TagNode
+	}{
+		{
+			name:   "empty",
+			counts: map[string]int{},
+			want:   &TagNode{},
+		},
+		{
+			name: "flat tags",
+			counts: map[string]int{
+				"golang": 2,
+				"rust":   3,
+				"cli":    2,
+			},
+			want: &TagNode{
+				Total: 7,
+				Children: []*TagNode{
+					{Name: "rust", Tag: "rust", Count: 3, Total: 3},
+					{Name: "cli", Tag: "cli", Count: 2, Total: 2},
+					{Name: "golang", Tag: "golang", Count: 2, Total: 2},
+				},
+			},
+		},
+		{
+			name: "nested tags",
+			counts: map[string]int{
+				"golang":               1,
+				"golang/cobra":         2,
+				"golang/cobra/Command": 3,
+				"golang/testing":       1,
+				"proj/alpha":           4,
+			},
+			want: &TagNode{
+				Total: 11,
+				Children: []*TagNode{
+					{
+						Name: "golang", Tag: "golang", Count: 1, Total: 7,
+						Children: []*TagNode{
+							{
+								Name: "cobra", Tag: "golang/cobra", Count: 2, Total: 5,
+								Children: []*TagNode{
+									{Name: "Command", Tag: "golang/cobra/Command", Count: 3, Total: 3},
+								},
+							},
+							{Name: "testing", Tag: "golang/testing", Count: 1, Total: 1},
+						},
+					},
+					{
+						// intermediate tags that are never used on their own
+						Name: "proj", Tag: "proj", Count: 0, Total: 4,
+						Children: []*TagNode{
+							{Name: "alpha", Tag: "proj/alpha", Count: 4, Total: 4},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.Equal(tt.want, NewTagTree(tt.counts))
+		})
+	}
+}
+
+func TestIsDescendant(t *testing.T) {
+	testCases := []struct {
+		tag      string
+		ancestor string
+		want     bool
+	}{
+		{tag: "golang/cobra", ancestor: "golang", want: true},
+		{tag: "golang/cobra/Command", ancestor: "golang", want: true},
+		{tag: "golang/cobra/Command", ancestor: "golang/cobra", want: true},
+		{tag: "golang", ancestor: "golang", want: false},
+		{tag: "golang", ancestor: "golang/cobra", want: false},
+		{tag: "golangci", ancestor: "golang", want: false},
+		{tag: "go/lang", ancestor: "golang", want: false},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.tag+" "+tt.ancestor, func(_ *testing.T) {
+			r.Equal(tt.want, IsDescendant(tt.tag, tt.ancestor))
+		})
+	}
+}
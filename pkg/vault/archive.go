@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// archiveExts are the extensions of the archives supported by OpenArchive.
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether the file at path is an archive supported by
+// OpenArchive, judging by its extension only.
+func IsArchive(path string) bool {
+	p := strings.ToLower(path)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenArchive opens the archive of a vault at path as a read-only fs.FS. Zip
+// archives and tarballs, optionally compressed with gzip, are supported. If
+// the archive holds a single top-level directory, as is common when archiving
+// a directory, the returned fs.FS is rooted at that directory.
+//
+// The returned io.Closer must be closed to release the archive.
+func OpenArchive(path string) (fs.FS, io.Closer, error) {
+	p := strings.ToLower(path)
+
+	var (
+		fsys   fs.FS
+		closer io.Closer = io.NopCloser(nil)
+	)
+	switch {
+	case strings.HasSuffix(p, ".zip"):
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		fsys, closer = zipFS{&zr.Reader}, zr
+	case strings.HasSuffix(p, ".tar"), strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		mfs, err := readTarball(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		fsys = mfs
+	default:
+		return nil, nil, fmt.Errorf("unsupported archive: %s", path)
+	}
+
+	sub, err := archiveRoot(fsys)
+	if err != nil {
+		closer.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return sub, closer, nil
+}
+
+// archiveRoot returns fsys, or the only top-level directory of fsys if there
+// is no other top-level entry. Metadata added by macOS is not an entry.
+func archiveRoot(fsys fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		return isMacMetadata(e.Name())
+	})
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return fsys, nil
+	}
+	return fs.Sub(fsys, entries[0].Name())
+}
+
+// readTarball reads the notes and the files configuring the scan of the
+// tarball at file into memory. Tarballs with a .gz or .tgz extension are
+// decompressed with gzip.
+func readTarball(file string) (*memFS, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if p := strings.ToLower(file); strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".tgz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	mfs := newMemFS()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if hdr.Typeflag != tar.TypeReg || !fs.ValidPath(name) || !keepArchived(name) {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		mfs.add(name, data, hdr.FileInfo().Mode(), hdr.ModTime)
+	}
+
+	return mfs, nil
+}
+
+// keepArchived reports whether the file at the slash-separated path name of a
+// tarball is needed for a scan: notes, ignore files, and exclude files, unless
+// they are metadata added by macOS.
+func keepArchived(name string) bool {
+	base := path.Base(name)
+	switch {
+	case isMacMetadata(name):
+		return false
+	case path.Ext(name) == ".md",
+		base == ".gitignore",
+		base == ".tobiignore",
+		base == ExcludeFile,
+		strings.HasSuffix(name, ".git/info/exclude"):
+		return true
+	}
+	return false
+}
+
+// isMacMetadata reports whether the file at the slash-separated path name of an
+// archive is metadata added by macOS: the __MACOSX directory of zip archives
+// made with Finder, or AppleDouble files, whose names start with "._".
+func isMacMetadata(name string) bool {
+	for seg := range strings.SplitSeq(name, "/") {
+		if seg == "__MACOSX" || strings.HasPrefix(seg, "._") {
+			return true
+		}
+	}
+	return false
+}
+
+// zipFS is a zip archive that hides the metadata added by macOS, so that it is
+// neither walked nor scanned.
+type zipFS struct {
+	r *zip.Reader
+}
+
+// Open implements fs.FS.
+func (z zipFS) Open(name string) (fs.File, error) {
+	if isMacMetadata(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return z.r.Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (z zipFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if isMacMetadata(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(z.r, name)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		return isMacMetadata(e.Name())
+	}), nil
+}
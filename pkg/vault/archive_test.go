@@ -0,0 +1,237 @@
+package vault
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var archiveFiles = map[string]string{
+	"note1.md":       "#golang #daily",
+	"sub/note2.md":   "#cobra",
+	"sub/ignored.md": "#secret",
+	".gitignore":     "sub/ignored.md",
+	ExcludeFile:      "daily",
+	"image.png":      "#png",
+}
+
+// writeZip writes files to a zip archive at path.
+func writeZip(t *testing.T, path string, files map[string]string) {
+	r := require.New(t)
+
+	f, err := os.Create(path)
+	r.NoError(err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		r.NoError(err)
+		_, err = io.WriteString(w, content)
+		r.NoError(err)
+	}
+	r.NoError(zw.Close())
+}
+
+// writeTar writes files to a tarball at path, compressed with gzip if gz is
+// true.
+func writeTar(t *testing.T, path string, files map[string]string, gz bool) {
+	r := require.New(t)
+
+	f, err := os.Create(path)
+	r.NoError(err)
+	defer f.Close()
+
+	var w io.Writer = f
+	if gz {
+		gw := gzip.NewWriter(f)
+		defer func() { r.NoError(gw.Close()) }()
+		w = gw
+	}
+
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		r.NoError(tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := io.WriteString(tw, content)
+		r.NoError(err)
+	}
+	r.NoError(tw.Close())
+}
+
+// prefixed returns files with every name prefixed with dir.
+func prefixed(dir string, files map[string]string) map[string]string {
+	out := make(map[string]string, len(files))
+	for name, content := range files {
+		out[dir+name] = content
+	}
+	return out
+}
+
+func TestIsArchive(t *testing.T) {
+	testCases := []struct {
+		path string
+		want bool
+	}{
+		{path: "vault.zip", want: true},
+		{path: "backup/Vault.ZIP", want: true},
+		{path: "vault.tar", want: true},
+		{path: "vault.tar.gz", want: true},
+		{path: "vault.tgz", want: true},
+		{path: "vault", want: false},
+		{path: "vault.gz", want: false},
+		{path: "note.md", want: false},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.path, func(_ *testing.T) {
+			r.Equal(tt.want, IsArchive(tt.path))
+		})
+	}
+}
+
+func TestOpen_Archive(t *testing.T) {
+	testCases := []struct {
+		name  string
+		file  string
+		write func(t *testing.T, path string)
+	}{
+		{
+			name: "zip",
+			file: "vault.zip",
+			write: func(t *testing.T, path string) {
+				writeZip(t, path, archiveFiles)
+			},
+		},
+		{
+			name: "zip with top-level directory",
+			file: "vault.zip",
+			write: func(t *testing.T, path string) {
+				writeZip(t, path, prefixed("vault/", archiveFiles))
+			},
+		},
+		{
+			name: "zip made with macOS Finder",
+			file: "vault.zip",
+			write: func(t *testing.T, path string) {
+				files := prefixed("vault/", archiveFiles)
+				files["__MACOSX/._vault"] = "#apple"
+				files["__MACOSX/vault/._note1.md"] = "#apple"
+				files["__MACOSX/vault/sub/._note2.md"] = "#apple"
+				files["vault/._note1.md"] = "#apple"
+				writeZip(t, path, files)
+			},
+		},
+		{
+			name: "tar",
+			file: "vault.tar",
+			write: func(t *testing.T, path string) {
+				writeTar(t, path, prefixed("./", archiveFiles), false)
+			},
+		},
+		{
+			name: "tar.gz with top-level directory",
+			file: "vault.tar.gz",
+			write: func(t *testing.T, path string) {
+				writeTar(t, path, prefixed("vault/", archiveFiles), true)
+			},
+		},
+		{
+			name: "tar.gz made on macOS",
+			file: "vault.tar.gz",
+			write: func(t *testing.T, path string) {
+				files := prefixed("vault/", archiveFiles)
+				files["._vault"] = "#apple"
+				files["vault/._note1.md"] = "#apple"
+				files["vault/sub/._note2.md"] = "#apple"
+				writeTar(t, path, files, true)
+			},
+		},
+		{
+			name: "tgz with unsafe paths",
+			file: "vault.tgz",
+			write: func(t *testing.T, path string) {
+				files := prefixed("", archiveFiles)
+				files["../escape.md"] = "#escape"
+				files["/abs.md"] = "#abs"
+				writeTar(t, path, files, true)
+			},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			tt.write(t, path)
+
+			s, err := Open(path, WithExcludeFile(), WithVersion("test"))
+			r.NoError(err)
+			defer s.Close()
+
+			res, err := s.Scan(context.Background())
+			r.NoError(err)
+			r.Equal(path, res.Root)
+			r.Equal(map[string]int{"golang": 1, "cobra": 1}, res.Counts)
+			r.Equal([]Note{
+				{Path: "note1.md", Tags: []string{"golang"}},
+				{Path: "sub/note2.md", Tags: []string{"cobra"}},
+			}, res.Notes)
+
+			got, err := s.ReadNote("note1.md")
+			r.NoError(err)
+			r.Equal("#golang #daily", got)
+
+			r.ErrorIs(s.WriteNote("note1.md", "#go"), ErrReadOnly)
+			r.NoFileExists(filepath.Join(filepath.Dir(path), CacheFile))
+		})
+	}
+}
+
+func TestOpenArchive_ErrorCases(t *testing.T) {
+	dir := t.TempDir()
+
+	corrupted := filepath.Join(dir, "corrupted.zip")
+	require.NoError(t, os.WriteFile(corrupted, []byte("not a zip"), 0o644))
+
+	notGzip := filepath.Join(dir, "plain.tar.gz")
+	writeTar(t, notGzip, archiveFiles, false)
+
+	testCases := []struct {
+		name string
+		path string
+		err  error
+	}{
+		{name: "missing archive", path: filepath.Join(dir, "missing.zip"), err: fs.ErrNotExist},
+		{name: "corrupted zip", path: corrupted},
+		{name: "tarball not compressed", path: notGzip},
+		{name: "unsupported archive", path: filepath.Join(dir, "vault.rar")},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			_, _, err := OpenArchive(tt.path)
+			r.Error(err)
+			if tt.err != nil {
+				r.ErrorIs(err, tt.err)
+			}
+		})
+	}
+}
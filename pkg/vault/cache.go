@@ -0,0 +1,153 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+)
+
+// cacheVersion is the version of the cache file format. Caches written with a
+// different version, including the unversioned caches of older releases that
+// only stored aggregate counts, are discarded and rebuilt from scratch.
+const cacheVersion = 1
+
+// cacheEntry is the cached state of a single note.
+type cacheEntry struct {
+	noteStat
+	Tags []string `json:"tags"`
+}
+
+// noteCache is the content of the .tobi.json cache file. It stores the tags
+// extracted from every note, keyed by the note's vault-relative path, so that
+// only added or changed notes need to be read again.
+//
+// Cached tags are unfiltered: tag excludes are applied when counts are
+// aggregated, and ignore rules are applied when the vault is walked, so editing
+// .tobi.exclude, .gitignore or .tobiignore takes effect without a rescan.
+type noteCache struct {
+	Version int `json:"version"`
+	// Tobi is the version of tobi that wrote the cache. Tag extraction rules
+	// may change between releases, so caches written by another version are
+	// discarded.
+	Tobi  string                `json:"tobi"`
+	Notes map[string]cacheEntry `json:"notes"`
+}
+
+func newNoteCache(tobiVersion string) noteCache {
+	return noteCache{
+		Version: cacheVersion,
+		Tobi:    tobiVersion,
+		Notes:   make(map[string]cacheEntry),
+	}
+}
+
+// readCache reads the cache file of the vault at root.
+//
+// Returns an error if the file cannot be read or decoded, or if it was written
+// with a different cache format version or by a different version of tobi.
+func readCache(root vaultPath, tobiVersion string) (noteCache, error) {
+	c, err := readCacheFile(root.cachePath())
+	if err != nil {
+		return noteCache{}, err
+	}
+	if c.Tobi != tobiVersion {
+		return noteCache{}, fmt.Errorf("cache was written by tobi %q", c.Tobi)
+	}
+	return c, nil
+}
+
+// readCacheFile reads the cache file at path, written by any version of tobi.
+//
+// Returns an error if the file cannot be read or decoded, or if it was written
+// with a different cache format version.
+func readCacheFile(path string) (noteCache, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return noteCache{}, err
+	}
+	defer f.Close()
+
+	var c noteCache
+	if err := json.NewDecoder(f).Decode(&c); err != nil {
+		return noteCache{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Version != cacheVersion {
+		return noteCache{}, fmt.Errorf("%s: unsupported cache version %d", path, c.Version)
+	}
+	if c.Notes == nil {
+		c.Notes = make(map[string]cacheEntry)
+	}
+	return c, nil
+}
+
+// write writes the cache to the cache file of the vault at root.
+// The file is written without indentation to keep it small on large vaults.
+//
+// The file is replaced atomically, so that an interrupted write never leaves a
+// partially written cache behind.
+func (c noteCache) write(root vaultPath) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(root.cachePath(), append(data, '\n'), 0o644)
+}
+
+// refresh brings the cache up to date with the notes in ns. Entries of removed
+// notes are dropped, and tags are extracted again only from notes that were
+// added or whose size or modification time changed.
+//
+// At most jobs notes are read concurrently, and their tags are stored as they
+// are extracted. Non-positive values of jobs mean GOMAXPROCS.
+//
+// Returns true if the cache was modified.
+func (c *noteCache) refresh(ctx context.Context, ns noteSet, jobs int) bool {
+	modified := false
+
+	for p := range c.Notes {
+		if _, ok := ns.notes[p]; !ok {
+			delete(c.Notes, p)
+			modified = true
+		}
+	}
+
+	var stale []string
+	for p, st := range ns.notes {
+		if e, ok := c.Notes[p]; !ok || e.noteStat != st {
+			stale = append(stale, p)
+		}
+	}
+
+	for p, tags := range collectTags(ctx, ns.fsys, stale, jobs) {
+		c.Notes[p] = cacheEntry{noteStat: ns.notes[p], Tags: tags}
+		modified = true
+	}
+
+	return modified
+}
+
+// update updates the entries of the given notes, keyed by path, with their new
+// content, which must have been written to the vault at root, and writes the
+// cache. Unlike refresh, the vault is not walked again, and tags are extracted
+// from the given content rather than read back.
+func (c *noteCache) update(root vaultPath, notes map[string]string) error {
+	for p, content := range notes {
+		info, err := os.Stat(root.join(p))
+		if err != nil {
+			return err
+		}
+
+		tags, err := tagx.Extract(content)
+		if err != nil {
+			return err
+		}
+
+		c.Notes[p] = cacheEntry{noteStat: newNoteStat(info), Tags: tags}
+	}
+
+	return c.write(root)
+}
@@ -0,0 +1,224 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_readCache(t *testing.T) {
+	testCases := []struct {
+		name    string
+		dir     *fs.Dir
+		want    noteCache
+		wantErr bool
+	}{
+		{
+			name: "reads from .tobi.json",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile(".tobi.json", `{"version":1,"tobi":"test","notes":{"a.md":{"size":10,"mtime":20,"tags":["golang","cobra"]}}}`),
+			),
+			want: noteCache{
+				Version: cacheVersion,
+				Tobi:    "test",
+				Notes: map[string]cacheEntry{
+					"a.md": {
+						noteStat: noteStat{Size: 10, ModTime: 20},
+						Tags:     []string{"golang", "cobra"},
+					},
+				},
+			},
+		},
+		{
+			name: "no notes",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile(".tobi.json", `{"version":1,"tobi":"test"}`),
+			),
+			want: noteCache{
+				Version: cacheVersion,
+				Tobi:    "test",
+				Notes:   map[string]cacheEntry{},
+			},
+		},
+		{
+			name: "discards unversioned cache",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile(".tobi.json", `{"tags":{"golang":5,"cobra":3},"hash":12345678901234567890}`),
+			),
+			wantErr: true,
+		},
+		{
+			name: "discards other versions",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile(".tobi.json", `{"version":99,"notes":{}}`),
+			),
+			wantErr: true,
+		},
+		{
+			name: "discards caches written by other tobi versions",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile(".tobi.json", `{"version":1,"tobi":"0.0.1","notes":{}}`),
+			),
+			wantErr: true,
+		},
+		{
+			name: "corrupted",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile(".tobi.json", `{"version":1,"notes":`),
+			),
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			dir:     fs.NewDir(t, "test"),
+			wantErr: true,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		defer tt.dir.Remove()
+
+		t.Run(tt.name, func(_ *testing.T) {
+			root, err := newVaultPath(tt.dir.Path())
+			r.NoError(err)
+
+			result, err := readCache(root, "test")
+			if tt.wantErr {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.want, result)
+		})
+	}
+}
+
+func Test_noteCache_write(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test")
+	defer dir.Remove()
+
+	root, err := newVaultPath(dir.Path())
+	r.NoError(err)
+
+	c := newNoteCache("test")
+	c.Notes["a.md"] = cacheEntry{
+		noteStat: noteStat{Size: 10, ModTime: 20},
+		Tags:     []string{"golang"},
+	}
+
+	r.NoError(c.write(root))
+
+	content, err := os.ReadFile(root.cachePath())
+	r.NoError(err)
+	r.JSONEq(`{"version":1,"tobi":"test","notes":{"a.md":{"size":10,"mtime":20,"tags":["golang"]}}}`, string(content))
+
+	// round trip
+	result, err := readCache(root, "test")
+	r.NoError(err)
+	r.Equal(c, result)
+}
+
+func Test_noteCache_refresh(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"keep.md":   "#golang",
+			"change.md": "#cobra",
+			"remove.md": "#cli",
+		}),
+	)
+	defer dir.Remove()
+
+	root, err := newVaultPath(dir.Path())
+	r.NoError(err)
+
+	ns, err := listNotes(context.Background(), os.DirFS(root.String()), true)
+	r.NoError(err)
+
+	c := newNoteCache("test")
+	r.True(c.refresh(context.Background(), ns, 0))
+	r.Equal(map[string][]string{
+		"keep.md":   {"golang"},
+		"change.md": {"cobra"},
+		"remove.md": {"cli"},
+	}, cachedTags(c))
+
+	// nothing changed
+	r.False(c.refresh(context.Background(), ns, 0))
+
+	// a cached entry is trusted as long as the note's size and mtime are unchanged
+	e := c.Notes["keep.md"]
+	e.Tags = []string{"cached"}
+	c.Notes["keep.md"] = e
+
+	r.NoError(os.WriteFile(dir.Join("change.md"), []byte("#cobra #changed"), 0o644))
+	later := time.Now().Add(time.Minute)
+	r.NoError(os.Chtimes(dir.Join("change.md"), later, later))
+	r.NoError(os.Remove(dir.Join("remove.md")))
+	r.NoError(os.WriteFile(dir.Join("add.md"), []byte("#added"), 0o644))
+
+	ns, err = listNotes(context.Background(), os.DirFS(root.String()), true)
+	r.NoError(err)
+
+	r.True(c.refresh(context.Background(), ns, 0))
+	r.Equal(map[string][]string{
+		"keep.md":   {"cached"},
+		"change.md": {"cobra", "changed"},
+		"add.md":    {"added"},
+	}, cachedTags(c))
+}
+
+func Test_noteCache_update(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"keep.md":   "#golang",
+			"change.md": "#cobra",
+		}),
+	)
+	defer dir.Remove()
+
+	root, err := newVaultPath(dir.Path())
+	r.NoError(err)
+
+	ns, err := listNotes(context.Background(), os.DirFS(root.String()), true)
+	r.NoError(err)
+
+	c := newNoteCache("test")
+	c.refresh(context.Background(), ns, 0)
+
+	r.NoError(os.WriteFile(dir.Join("change.md"), []byte("#cli"), 0o644))
+	r.NoError(c.update(root, map[string]string{"change.md": "#cli"}))
+	r.Equal(map[string][]string{
+		"keep.md":   {"golang"},
+		"change.md": {"cli"},
+	}, cachedTags(c))
+
+	// the written cache is up to date with the vault
+	got, err := readCache(root, "test")
+	r.NoError(err)
+
+	ns, err = listNotes(context.Background(), os.DirFS(root.String()), true)
+	r.NoError(err)
+	r.False(got.refresh(context.Background(), ns, 0))
+	r.Equal(cachedTags(c), cachedTags(got))
+}
+
+func cachedTags(c noteCache) map[string][]string {
+	m := make(map[string][]string, len(c.Notes))
+	for p, e := range c.Notes {
+		m[p] = e.Tags
+	}
+	return m
+}
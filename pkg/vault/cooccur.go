@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Pair is a pair of tags carried together by at least one note.
+type Pair struct {
+	// A and B are the tags of the pair, with A sorted before B, or A being the
+	// queried tag in the result of Cooccurrence.Related.
+	A, B string
+	// Notes is the number of notes carrying both tags.
+	Notes int
+	// Jaccard is the number of notes carrying both tags over the number of
+	// notes carrying either, from 0 to 1.
+	Jaccard float64
+	// PMI is the pointwise mutual information of the tags, in bits: how much
+	// more often the tags are carried together than if they were independent.
+	// It is 0 for independent tags, and grows for tags rarely used apart.
+	PMI float64
+}
+
+// Cooccurrence counts how many notes carry every tag, and every pair of tags.
+// A note carrying a tag several times counts once.
+type Cooccurrence struct {
+	notes int
+	tags  map[string]int
+	pairs map[[2]string]int
+}
+
+// NewCooccurrence counts the tags carried together by notes, as found in
+// Result.Notes. Cached scans keep the tags of every note, so the counts are
+// computed without reading any note again.
+func NewCooccurrence(notes []Note) *Cooccurrence {
+	c := &Cooccurrence{
+		notes: len(notes),
+		tags:  make(map[string]int),
+		pairs: make(map[[2]string]int),
+	}
+
+	for _, n := range notes {
+		tags := slices.Clone(n.Tags)
+		slices.Sort(tags)
+		tags = slices.Compact(tags)
+
+		for i, a := range tags {
+			c.tags[a]++
+			for _, b := range tags[i+1:] {
+				c.pairs[[2]string{a, b}]++
+			}
+		}
+	}
+
+	return c
+}
+
+// Notes returns the number of notes carrying tag.
+func (c *Cooccurrence) Notes(tag string) int {
+	return c.tags[tag]
+}
+
+// Pairs returns every pair of tags carried together by at least minNotes
+// notes, sorted by number of notes in descending order, then by tags.
+func (c *Cooccurrence) Pairs(minNotes int) []Pair {
+	pairs := make([]Pair, 0, len(c.pairs))
+	for k, n := range c.pairs {
+		if n >= minNotes {
+			pairs = append(pairs, c.pair(k[0], k[1], n))
+		}
+	}
+
+	slices.SortFunc(pairs, comparePairs)
+	return pairs
+}
+
+// Related returns the pairs of tag with every tag carried together with it
+// by at least minNotes notes, with tag as A, sorted like Pairs.
+func (c *Cooccurrence) Related(tag string, minNotes int) []Pair {
+	var pairs []Pair
+	for k, n := range c.pairs {
+		if n < minNotes {
+			continue
+		}
+		switch tag {
+		case k[0]:
+			pairs = append(pairs, c.pair(k[0], k[1], n))
+		case k[1]:
+			pairs = append(pairs, c.pair(k[1], k[0], n))
+		}
+	}
+
+	slices.SortFunc(pairs, comparePairs)
+	return pairs
+}
+
+// pair returns the Pair of tags a and b, carried together by n notes.
+func (c *Cooccurrence) pair(a, b string, n int) Pair {
+	na, nb := float64(c.tags[a]), float64(c.tags[b])
+	ab := float64(n)
+
+	return Pair{
+		A:       a,
+		B:       b,
+		Notes:   n,
+		Jaccard: ab / (na + nb - ab),
+		PMI:     math.Log2(ab * float64(c.notes) / (na * nb)),
+	}
+}
+
+func comparePairs(x, y Pair) int {
+	return cmp.Or(
+		cmp.Compare(y.Notes, x.Notes),
+		cmp.Compare(x.A, y.A),
+		cmp.Compare(x.B, y.B),
+	)
+}
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCooccurrence(t *testing.T) {
+	c := NewCooccurrence([]Note{
+		{Path: "a.md", Tags: []string{"golang", "cobra", "golang"}},
+		{Path: "b.md", Tags: []string{"golang", "cobra", "cli"}},
+		{Path: "c.md", Tags: []string{"golang"}},
+		{Path: "d.md", Tags: []string{"rust", "cli"}},
+		{Path: "e.md"},
+	})
+
+	// names returns the tags of every pair
+	names := func(pairs []Pair) [][2]string {
+		out := [][2]string{}
+		for _, p := range pairs {
+			out = append(out, [2]string{p.A, p.B})
+		}
+		return out
+	}
+
+	testCases := []struct {
+		name string
+		got  []Pair
+		want [][2]string
+	}{
+		{
+			name: "all pairs",
+			got:  c.Pairs(0),
+			want: [][2]string{{"cobra", "golang"}, {"cli", "cobra"}, {"cli", "golang"}, {"cli", "rust"}},
+		},
+		{
+			name: "pairs with min notes",
+			got:  c.Pairs(2),
+			want: [][2]string{{"cobra", "golang"}},
+		},
+		{
+			name: "related",
+			got:  c.Related("golang", 1),
+			want: [][2]string{{"golang", "cobra"}, {"golang", "cli"}},
+		},
+		{
+			name: "related with min notes",
+			got:  c.Related("cli", 2),
+			want: [][2]string{},
+		},
+		{
+			name: "unknown tag",
+			got:  c.Related("missing", 0),
+			want: [][2]string{},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.Equal(tt.want, names(tt.got))
+		})
+	}
+
+	r.Equal(3, c.Notes("golang"))
+	r.Equal(2, c.Notes("cobra"))
+	r.Equal(0, c.Notes("missing"))
+
+	r.Equal(Pair{
+		A:       "cobra",
+		B:       "golang",
+		Notes:   2,
+		Jaccard: 2.0 / 3,
+		PMI:     math.Log2(2 * 5.0 / (2 * 3)),
+	}, c.Pairs(0)[0])
+
+	// rust is only ever used with cli
+	p := c.Related("rust", 0)[0]
+	r.Equal(0.5, p.Jaccard)
+	r.InDelta(math.Log2(5.0/2), p.PMI, 1e-9)
+}
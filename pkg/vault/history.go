@@ -0,0 +1,236 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Period is the length of the periods commits are grouped by when sampling
+// the history of a vault.
+type Period int
+
+const (
+	// EveryCommit samples every commit.
+	EveryCommit Period = iota
+	// Daily samples the last commit of every day.
+	Daily
+	// Weekly samples the last commit of every week, starting on Monday.
+	Weekly
+	// Monthly samples the last commit of every month.
+	Monthly
+	// Yearly samples the last commit of every year.
+	Yearly
+)
+
+// start returns the start of the period holding t. Periods are computed in
+// UTC, so that they do not depend on the time zone of the committer.
+func (p Period) start(t time.Time) time.Time {
+	t = t.UTC()
+	y, m, d := t.Date()
+
+	switch p {
+	case Daily:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case Weekly:
+		// days since Monday
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// HistoryRange selects the commits sampled by History.
+type HistoryRange struct {
+	// Since and Until bound the committer time of sampled commits, inclusive.
+	// Zero values mean unbounded.
+	Since, Until time.Time
+	// Every groups commits by period and samples the last commit of every
+	// period. Defaults to EveryCommit.
+	Every Period
+}
+
+// Snapshot holds the tag counts of a vault as of a commit.
+type Snapshot struct {
+	// Commit is the hash of the commit.
+	Commit string
+	// Time is the committer time of the commit.
+	Time time.Time
+	// Period is the start of the period the commit was sampled for, or the
+	// time of the commit with EveryCommit.
+	Period time.Time
+	// Notes is the number of notes in the vault.
+	Notes int
+	// Counts is the number of occurrences of every tag across all notes.
+	Counts map[string]int
+	// Total is the number of tag occurrences across all notes.
+	Total int
+}
+
+// History returns the tag counts of the vault at the directory path as of the
+// commits on the first-parent history of HEAD selected by hr, oldest first.
+// Like OpenRevision, notes are read straight from the repository, honoring the
+// ignore files of every commit. Options configure the scan of every commit;
+// the cache policy is ignored.
+//
+// Tags are extracted once per distinct note content: notes unchanged between
+// sampled commits are not read again, so scanning a long history costs little
+// more than scanning the notes changed in it.
+//
+// Returns an error if the vault is not in a git repository, or if ctx is done
+// before the history is scanned.
+func History(ctx context.Context, path string, hr HistoryRange, opts ...Option) ([]Snapshot, error) {
+	root, err := newVaultPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, dir, err := openRepo(root)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := resolveCommit(repo, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := sampleCommits(ctx, head, hr)
+	if err != nil {
+		return nil, err
+	}
+
+	// tags of every note content seen so far, keyed by blob hash
+	blobs := make(map[plumbing.Hash][]string)
+
+	snapshots := make([]Snapshot, 0, len(commits))
+	for _, c := range commits {
+		snap, err := scanCommit(ctx, c.commit, dir, blobs, opts)
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", c.commit.Hash, err)
+		}
+		snap.Period = c.period
+		snapshots = append(snapshots, snap)
+	}
+
+	return snapshots, nil
+}
+
+// sampledCommit is a commit sampled for a period.
+type sampledCommit struct {
+	commit *object.Commit
+	period time.Time
+}
+
+// sampleCommits walks the first-parent history of head, and returns the
+// commits selected by hr, oldest first.
+func sampleCommits(ctx context.Context, head *object.Commit, hr HistoryRange) ([]sampledCommit, error) {
+	var commits []*object.Commit
+	for c := head; ; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		when := c.Committer.When
+		if !hr.Since.IsZero() && when.Before(hr.Since) {
+			break
+		}
+		if hr.Until.IsZero() || !when.After(hr.Until) {
+			commits = append(commits, c)
+		}
+
+		if c.NumParents() == 0 {
+			break
+		}
+		p, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		c = p
+	}
+	slices.Reverse(commits)
+
+	var sampled []sampledCommit
+	for _, c := range commits {
+		period := hr.Every.start(c.Committer.When)
+		// a later commit of the same period replaces the earlier one
+		if n := len(sampled); n > 0 && hr.Every != EveryCommit && sampled[n-1].period.Equal(period) {
+			sampled[n-1].commit = c
+			continue
+		}
+		sampled = append(sampled, sampledCommit{commit: c, period: period})
+	}
+
+	return sampled, nil
+}
+
+// scanCommit returns the tag counts of the vault at dir as of commit, which
+// are empty if dir did not exist yet. Notes whose blob is in blobs are not read
+// again, and the tags of the other notes are added to blobs.
+func scanCommit(ctx context.Context, commit *object.Commit, dir string, blobs map[plumbing.Hash][]string, opts []Option) (Snapshot, error) {
+	snap := Snapshot{
+		Commit: commit.Hash.String(),
+		Time:   commit.Committer.When,
+		Counts: make(map[string]int),
+	}
+
+	fsys, err := newTreeFS(commit, dir)
+	// the vault may be a directory added to the repository later
+	if errors.Is(err, fs.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	s := NewFSScanner(fsys, commit.Hash.String(), opts...)
+	exclude, err := s.excluder()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	ns, err := listNotes(ctx, fsys, s.ignoreRules)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	c := newNoteCache(s.version)
+	hashes := make(map[string]plumbing.Hash, len(ns.notes))
+	var stale []string
+	for p := range ns.notes {
+		info, err := fs.Stat(fsys, p)
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		h := info.Sys().(plumbing.Hash)
+		if tags, ok := blobs[h]; ok {
+			c.Notes[p] = cacheEntry{Tags: tags}
+			continue
+		}
+		hashes[p] = h
+		stale = append(stale, p)
+	}
+
+	for p, tags := range collectTags(ctx, fsys, stale, s.jobs) {
+		blobs[hashes[p]] = tags
+		c.Notes[p] = cacheEntry{Tags: tags}
+	}
+	if err := ctx.Err(); err != nil {
+		return Snapshot{}, err
+	}
+
+	res := s.result(c, exclude, false)
+	snap.Notes, snap.Counts, snap.Total = len(res.Notes), res.Counts, res.Total
+	return snap, nil
+}
@@ -0,0 +1,178 @@
+package vault
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriod_start(t *testing.T) {
+	// a Wednesday
+	ts := time.Date(2024, 3, 13, 15, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+
+	testCases := []struct {
+		name   string
+		period Period
+		want   time.Time
+	}{
+		{name: "commit", period: EveryCommit, want: time.Date(2024, 3, 13, 13, 4, 5, 0, time.UTC)},
+		{name: "daily", period: Daily, want: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "weekly", period: Weekly, want: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{name: "monthly", period: Monthly, want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "yearly", period: Yearly, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.Equal(tt.want, tt.period.start(ts))
+		})
+	}
+
+	// weeks starting on Sunday belong to the previous Monday
+	r.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Weekly.start(time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC)))
+}
+
+func TestHistory(t *testing.T) {
+	// commits are a day apart, starting on Monday 2024-01-01
+	g := newGitRepo(t)
+	g.commit("day 1", map[string]string{"README.md": "#readme"})
+	g.commit("day 2", map[string]string{"vault/a.md": "#golang"})
+	g.commit("day 3", map[string]string{"vault/b.md": "#golang #rust"})
+	g.commit("day 4", map[string]string{"vault/a.md": "#go"})
+	g.commit("day 5", map[string]string{"vault/.gitignore": "b.md"})
+	g.commit("day 6", map[string]string{"vault/c.md": "#go #rust"})
+	g.commit("day 7", map[string]string{"vault/d.md": "#go"})
+	g.commit("day 8", map[string]string{"vault/d.md": ""})
+	g.commit("day 9", map[string]string{"vault/.tobi.exclude": "rust"})
+
+	vault := filepath.Join(g.dir, "vault")
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name   string
+		hr     HistoryRange
+		want   []map[string]int
+		period []time.Time
+	}{
+		{
+			name: "every commit",
+			hr:   HistoryRange{},
+			want: []map[string]int{
+				{},
+				{"golang": 1},
+				{"golang": 2, "rust": 1},
+				{"go": 1, "golang": 1, "rust": 1},
+				{"go": 1},
+				{"go": 2, "rust": 1},
+				{"go": 3, "rust": 1},
+				{"go": 2, "rust": 1},
+				{"go": 2},
+			},
+		},
+		{
+			name: "since and until",
+			hr:   HistoryRange{Since: day(3), Until: day(5)},
+			want: []map[string]int{
+				{"golang": 2, "rust": 1},
+				{"go": 1, "golang": 1, "rust": 1},
+				{"go": 1},
+			},
+		},
+		{
+			name:   "weekly",
+			hr:     HistoryRange{Every: Weekly},
+			want:   []map[string]int{{"go": 3, "rust": 1}, {"go": 2}},
+			period: []time.Time{day(1), day(8)},
+		},
+		{
+			name:   "monthly since",
+			hr:     HistoryRange{Since: day(6), Every: Monthly},
+			want:   []map[string]int{{"go": 2}},
+			period: []time.Time{day(1)},
+		},
+		{
+			name: "no commits in range",
+			hr:   HistoryRange{Since: day(20)},
+			want: []map[string]int{},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			snapshots, err := History(context.Background(), vault, tt.hr, WithExcludeFile(), WithJobs(2))
+			r.NoError(err)
+
+			got := make([]map[string]int, 0, len(snapshots))
+			var periods []time.Time
+			for _, s := range snapshots {
+				got = append(got, s.Counts)
+				periods = append(periods, s.Period)
+			}
+			r.Equal(tt.want, got)
+			if tt.period != nil {
+				r.Equal(tt.period, periods)
+			}
+		})
+	}
+
+	r.NoFileExists(filepath.Join(vault, CacheFile))
+}
+
+func Test_scanCommit_blobs(t *testing.T) {
+	r := require.New(t)
+
+	g := newGitRepo(t)
+	g.commit("first", map[string]string{"a.md": "#golang", "b.md": "#golang"})
+	g.commit("second", map[string]string{"c.md": "#rust"})
+
+	head, err := g.repo.Head()
+	r.NoError(err)
+	second, err := g.repo.CommitObject(head.Hash())
+	r.NoError(err)
+	first, err := second.Parent(0)
+	r.NoError(err)
+
+	ctx := context.Background()
+	blobs := make(map[plumbing.Hash][]string)
+
+	snap, err := scanCommit(ctx, first, ".", blobs, nil)
+	r.NoError(err)
+	r.Equal(map[string]int{"golang": 2}, snap.Counts)
+	// a.md and b.md share a blob
+	r.Len(blobs, 1)
+
+	// tags of known blobs are reused rather than read again
+	for h := range blobs {
+		blobs[h] = []string{"cached"}
+	}
+	snap, err = scanCommit(ctx, second, ".", blobs, nil)
+	r.NoError(err)
+	r.Equal(map[string]int{"cached": 2, "rust": 1}, snap.Counts)
+	r.Equal(3, snap.Notes)
+	r.Len(blobs, 2)
+}
+
+func TestHistory_ErrorCases(t *testing.T) {
+	r := require.New(t)
+
+	_, err := History(context.Background(), t.TempDir(), HistoryRange{})
+	r.Error(err)
+
+	g := newGitRepo(t)
+	g.commit("initial", map[string]string{"note.md": "#golang"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = History(ctx, g.dir, HistoryRange{})
+	r.ErrorIs(err, context.Canceled)
+}
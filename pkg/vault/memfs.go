@@ -0,0 +1,174 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// memFS is a read-only fs.FS over files held in memory, keyed by their
+// slash-separated path. Directories are implied by the paths of the files
+// they hold. Use add to fill it, before any use.
+type memFS struct {
+	entries map[string]*memEntry
+	// children are the names of the entries of every directory, in no
+	// particular order.
+	children map[string][]string
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		entries:  map[string]*memEntry{".": {name: ".", mode: fs.ModeDir | 0o555}},
+		children: make(map[string][]string),
+	}
+}
+
+// add adds the file at the valid, slash-separated path name, and the
+// directories holding it. A file added twice is replaced.
+func (m *memFS) add(name string, data []byte, mode fs.FileMode, modTime time.Time) {
+	if _, ok := m.entries[name]; !ok {
+		m.link(name)
+	}
+	m.entries[name] = &memEntry{name: path.Base(name), data: data, mode: mode.Perm(), modTime: modTime}
+}
+
+// link adds name to the children of its directory, creating the directory and
+// its parents as needed.
+func (m *memFS) link(name string) {
+	dir := path.Dir(name)
+	m.children[dir] = append(m.children[dir], path.Base(name))
+	if _, ok := m.entries[dir]; !ok {
+		m.entries[dir] = &memEntry{name: path.Base(dir), mode: fs.ModeDir | 0o555}
+		m.link(dir)
+	}
+}
+
+// lookup returns the entry at name.
+func (m *memFS) lookup(name string) (*memEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, fs.ErrInvalid
+	}
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return e, nil
+}
+
+// Open implements fs.FS.
+func (m *memFS) Open(name string) (fs.File, error) {
+	e, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if e.IsDir() {
+		entries, err := m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &memDir{info: e, entries: entries}, nil
+	}
+	return &memFile{info: e, Reader: bytes.NewReader(e.data)}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	e, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	if e.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return slices.Clone(e.data), nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if !e.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(m.children[name]))
+	for _, c := range m.children[name] {
+		entries = append(entries, fs.FileInfoToDirEntry(m.entries[path.Join(name, c)]))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// Stat implements fs.StatFS.
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	e, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return e, nil
+}
+
+// memEntry is a file or directory of a memFS, and implements fs.FileInfo.
+type memEntry struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (e *memEntry) Name() string       { return e.name }
+func (e *memEntry) Size() int64        { return int64(len(e.data)) }
+func (e *memEntry) Mode() fs.FileMode  { return e.mode }
+func (e *memEntry) ModTime() time.Time { return e.modTime }
+func (e *memEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *memEntry) Sys() any           { return nil }
+
+// memFile is an open file of a memFS.
+type memFile struct {
+	info *memEntry
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory of a memFS.
+type memDir struct {
+	info    *memEntry
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,45 @@
+package vault
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemFS(t *testing.T) {
+	r := require.New(t)
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := newMemFS()
+	m.add("a.md", []byte("#golang"), 0o644, mtime)
+	m.add("notes/deep/b.md", []byte("#rust"), 0o600, mtime)
+	m.add("notes/c.md", []byte("#cli"), fs.ModeSymlink|0o777, mtime)
+	m.add("notes/c.md", []byte("#cli #go"), 0o644, mtime)
+
+	r.NoError(fstest.TestFS(m, "a.md", "notes/deep/b.md", "notes/c.md"))
+
+	data, err := fs.ReadFile(m, "notes/c.md")
+	r.NoError(err)
+	r.Equal("#cli #go", string(data))
+
+	entries, err := fs.ReadDir(m, "notes")
+	r.NoError(err)
+	r.Len(entries, 2)
+	r.Equal("c.md", entries[0].Name())
+	r.True(entries[1].IsDir())
+
+	info, err := fs.Stat(m, "notes/deep/b.md")
+	r.NoError(err)
+	r.Equal(fs.FileMode(0o600), info.Mode())
+	r.Equal(mtime, info.ModTime())
+
+	_, err = m.Open("missing.md")
+	r.ErrorIs(err, fs.ErrNotExist)
+	_, err = m.Open("../a.md")
+	r.ErrorIs(err, fs.ErrInvalid)
+	_, err = m.ReadFile("notes")
+	r.Error(err)
+}
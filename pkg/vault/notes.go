@@ -0,0 +1,236 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"iter"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/nt54hamnghi/tobi/pkg/gitignore"
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// vaultPath is an absolute path to a valid directory.
+type vaultPath string
+
+func newVaultPath(path string) (vaultPath, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+	return vaultPath(path), nil
+}
+
+func (v vaultPath) String() string {
+	return string(v)
+}
+
+func (v vaultPath) cachePath() string {
+	return filepath.Join(v.String(), CacheFile)
+}
+
+// join returns the absolute path of the note at the slash-separated,
+// vault-relative path rel.
+func (v vaultPath) join(rel string) string {
+	return filepath.Join(v.String(), filepath.FromSlash(rel))
+}
+
+// noteStat holds the file metadata used to detect changed notes.
+type noteStat struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"` // Unix time in nanoseconds
+}
+
+func newNoteStat(info fs.FileInfo) noteStat {
+	return noteStat{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+	}
+}
+
+// noteSet represents a collection of discovered note files in a vault, keyed by
+// their slash-separated, vault-relative path. The size and modification time of
+// every note are recorded to detect changes against the cache.
+type noteSet struct {
+	fsys  fs.FS
+	notes map[string]noteStat
+}
+
+// listNotes recursively traverses the file system fsys, rooted at the vault
+// root, and discovers all '.md' files that should be tracked, filtering out files
+// ignored by .gitignore patterns if ignoreRules is true and skipping the .git
+// directory. It returns a noteSet containing the discovered files along with
+// their size and modification time.
+//
+// Files that cannot be accessed for file info are logged and skipped.
+//
+// Returns an error if .gitignore patterns cannot be read, or ctx is done before
+// the traversal completes.
+func listNotes(ctx context.Context, fsys fs.FS, ignoreRules bool) (noteSet, error) {
+	var m *gitignore.FSMatcher
+	if ignoreRules {
+		fm, err := gitignore.NewFSMatcher(ctx, fsys)
+		if err != nil {
+			return noteSet{}, err
+		}
+		m = &fm
+	}
+
+	notes := make(map[string]noteStat)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Skip directory entry if there's an error
+		if err != nil {
+			return nil
+		}
+
+		// Skip .git directory
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+
+		if d.Type().IsRegular() && path.Ext(name) == ".md" {
+			if m != nil && m.MatchFile(name) {
+				return nil
+			}
+
+			info, err := d.Info()
+			// Skip files where we can't get info. Info() returns fs.ErrNotExist if the file
+			// has been removed or renamed since the directory read. Since we're only reading
+			// (not modifying files), this should never happen. However, we log the error
+			// as a safeguard to warn anyone against accidentally modifying files during traversal.
+			if err != nil {
+				log.Printf("failed to get file info for %s: %v", name, err)
+				return nil
+			}
+
+			notes[name] = newNoteStat(info)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return noteSet{}, err
+	}
+
+	return noteSet{
+		fsys:  fsys,
+		notes: notes,
+	}, nil
+}
+
+// collectTags reads the given notes and extracts tags from their YAML
+// frontmatter and body. Paths are vault-relative and slash-separated. Returns an
+// iterator over the extracted tags keyed by path, in no particular order.
+//
+// Notes flow through the bounded pipeline of mapNotes. Files that cannot be
+// processed due to errors are logged and skipped.
+func collectTags(ctx context.Context, fsys fs.FS, paths []string, jobs int) iter.Seq2[string, []string] {
+	return mapNotes(ctx, fsys, paths, jobs, readTags)
+}
+
+// mapNotes calls read for every note of paths, vault-relative and
+// slash-separated, and returns an iterator over the results for which read
+// returns ok, keyed by path, in no particular order.
+//
+// Notes flow through a bounded pipeline: at most jobs notes are read at once,
+// and each result is yielded as soon as it is ready, so that memory use and
+// open files do not grow with the size of the vault. Non-positive values of
+// jobs mean GOMAXPROCS.
+//
+// No more notes are read once ctx is done; callers should check ctx.Err() after
+// the iteration.
+func mapNotes[T any](ctx context.Context, fsys fs.FS, paths []string, jobs int, read func(fsys fs.FS, n string) (T, bool)) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		if len(paths) == 0 {
+			return
+		}
+		if jobs <= 0 {
+			jobs = runtime.GOMAXPROCS(0)
+		}
+
+		type result struct {
+			path  string
+			value T
+		}
+
+		results := make(chan result, jobs)
+		// done is closed when the consumer stops early, to release the workers
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			defer close(results)
+
+			p := pool.New().WithMaxGoroutines(jobs)
+			defer p.Wait()
+
+			for _, n := range paths {
+				select {
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				// Go blocks while jobs notes are in flight
+				p.Go(func() {
+					if ctx.Err() != nil {
+						return
+					}
+
+					v, ok := read(fsys, n)
+					if !ok {
+						return
+					}
+					select {
+					case results <- result{path: n, value: v}:
+					case <-done:
+					}
+				})
+			}
+		}()
+
+		for r := range results {
+			if !yield(r.path, r.value) {
+				return
+			}
+		}
+	}
+}
+
+// readTags reads the note at the vault-relative path n and extracts its tags.
+// Errors are logged, and ok is false.
+func readTags(fsys fs.FS, n string) (tags []string, ok bool) {
+	f, err := fs.ReadFile(fsys, n)
+	if err != nil {
+		log.Printf("failed to open file %s: %v", n, err)
+		return nil, false
+	}
+
+	tags, err = tagx.Extract(string(f))
+	if err != nil {
+		log.Printf("failed to extract tags from file %s: %v", n, err)
+		return nil, false
+	}
+
+	return tags, true
+}
@@ -0,0 +1,291 @@
+package vault
+
+import (
+	"context"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_listNotes(t *testing.T) {
+	testCases := []struct {
+		name string
+		dir  *fs.Dir
+		want []string
+	}{
+		{
+			name: "single",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile("note.md", "# Test"),
+			),
+			want: []string{"note.md"},
+		},
+		{
+			name: "multiple",
+			dir: fs.NewDir(t, "test",
+				fs.WithFiles(map[string]string{
+					"note1.md": "# Test 1",
+					"note2.md": "# Test 2",
+				}),
+			),
+			want: []string{"note1.md", "note2.md"},
+		},
+		{
+			name: "mixed file types",
+			dir: fs.NewDir(t, "test",
+				fs.WithFiles(map[string]string{
+					"note1.md": "# Test 1",
+					"t.txt":    "Text file",
+					"t.json":   `{"key": "value"}`,
+					"t.sh":     "#!/bin/bash",
+				}),
+			),
+			want: []string{"note1.md"},
+		},
+		{
+			name: "nested",
+			dir: fs.NewDir(t, "test",
+				fs.WithDir("level1",
+					fs.WithFile("note.md", "# Nested"),
+				),
+			),
+			want: []string{"level1/note.md"},
+		},
+		{
+			name: "deeply nested",
+			dir: fs.NewDir(t, "test",
+				fs.WithDir("level1",
+					fs.WithDir("level2",
+						fs.WithFile("note.md", "# Deep"),
+					),
+				),
+			),
+			want: []string{"level1/level2/note.md"},
+		},
+		{
+			name: ".git skipped",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile("note.md", "# Note"),
+				fs.WithDir(".git",
+					fs.WithFile("file.md", "# Ignored"),
+				),
+			),
+			want: []string{"note.md"},
+		},
+		{
+			name: "empty directory",
+			dir:  fs.NewDir(t, "test"),
+			want: []string{},
+		},
+		{
+			name: "root .gitignore",
+			dir: fs.NewDir(t, "test",
+				fs.WithFiles(map[string]string{
+					".gitignore": "level1/",
+					"note.md":    "content",
+				}),
+				fs.WithDir("level1",
+					fs.WithFiles(map[string]string{
+						"note2.md": "content",
+					}),
+				),
+			),
+			want: []string{"note.md"},
+		},
+		{
+			name: "subdir .gitignore",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile("note.md", "content"),
+				fs.WithDir("level1",
+					fs.WithFiles(map[string]string{
+						"note2.md": "content",
+					}),
+				),
+			),
+			want: []string{"note.md", "level1/note2.md"},
+		},
+		{
+			name: "multiple .gitignore",
+			dir: fs.NewDir(t, "test",
+				fs.WithFiles(map[string]string{
+					".gitignore": "level1/",
+					"note.md":    "content",
+				}),
+				fs.WithDir("level1",
+					fs.WithFiles(map[string]string{
+						"note2.md": "content",
+					}),
+				),
+				fs.WithDir("level2",
+					fs.WithFiles(map[string]string{
+						"note3.md":   "content",
+						"note4.md":   "content",
+						".gitignore": "note4.md",
+					}),
+				),
+			),
+			want: []string{"note.md", "level2/note3.md"},
+		},
+	}
+
+	r := require.New(t)
+	for _, tt := range testCases {
+		defer tt.dir.Remove()
+
+		t.Run(tt.name, func(_ *testing.T) {
+			root, err := newVaultPath(tt.dir.Path())
+			r.NoError(err)
+
+			ns, err := listNotes(context.Background(), os.DirFS(root.String()), true)
+			r.NoError(err)
+
+			relPaths := slices.AppendSeq([]string{}, maps.Keys(ns.notes))
+
+			// Sort both slices for reliable comparison
+			sort.Strings(relPaths)
+			sort.Strings(tt.want)
+
+			r.Equal(tt.want, relPaths)
+		})
+	}
+}
+
+func Test_listNotes_stat(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("note.md", "# Test"),
+	)
+	defer dir.Remove()
+
+	fsys := os.DirFS(dir.Path())
+
+	info, err := os.Stat(dir.Join("note.md"))
+	r.NoError(err)
+
+	ns, err := listNotes(context.Background(), fsys, true)
+	r.NoError(err)
+
+	r.Equal(fsys, ns.fsys)
+	r.Equal(map[string]noteStat{
+		"note.md": {Size: info.Size(), ModTime: info.ModTime().UnixNano()},
+	}, ns.notes)
+}
+
+func Test_listNotes_options(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"note.md":    "#golang",
+			"ignored.md": "#cobra",
+			".gitignore": "ignored.md",
+		}),
+	)
+	defer dir.Remove()
+
+	root, err := newVaultPath(dir.Path())
+	r.NoError(err)
+
+	// ignore rules can be disabled
+	ns, err := listNotes(context.Background(), os.DirFS(root.String()), false)
+	r.NoError(err)
+	r.ElementsMatch([]string{"note.md", "ignored.md"}, slices.Collect(maps.Keys(ns.notes)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = listNotes(ctx, os.DirFS(root.String()), true)
+	r.ErrorIs(err, context.Canceled)
+}
+
+func Test_collectTags(t *testing.T) {
+	testCases := []struct {
+		name  string
+		dir   *fs.Dir
+		paths []string
+		want  map[string][]string
+	}{
+		{
+			name: "single file",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile("note1.md", "---\ntags: [golang, cobra]\n---\nContent #cli"),
+			),
+			paths: []string{"note1.md"},
+			want: map[string][]string{
+				"note1.md": {"golang", "cobra", "cli"},
+			},
+		},
+		{
+			name: "multiple files",
+			dir: fs.NewDir(t, "test",
+				fs.WithFiles(map[string]string{
+					"note1.md": "---\ntags: [golang, cobra]\n---\nContent",
+					"note2.md": "Content #cli #golang",
+				}),
+				fs.WithDir("level1",
+					fs.WithFile("note3.md", "---\ntags: [cobra]\n---\nContent"),
+				),
+			),
+			paths: []string{"note1.md", "note2.md", "level1/note3.md"},
+			want: map[string][]string{
+				"note1.md":        {"golang", "cobra"},
+				"note2.md":        {"cli", "golang"},
+				"level1/note3.md": {"cobra"},
+			},
+		},
+		{
+			name: "only given paths",
+			dir: fs.NewDir(t, "test",
+				fs.WithFiles(map[string]string{
+					"note1.md": "Content #golang",
+					"note2.md": "Content #cobra",
+				}),
+			),
+			paths: []string{"note2.md"},
+			want: map[string][]string{
+				"note2.md": {"cobra"},
+			},
+		},
+		{
+			name: "skip files with errors",
+			dir: fs.NewDir(t, "test",
+				fs.WithFile("invalid.md", "---\ntags: [invalid: yaml\n---\nContent"),
+			),
+			paths: []string{"invalid.md", "missing.md"},
+			want:  map[string][]string{},
+		},
+		{
+			name:  "no paths",
+			dir:   fs.NewDir(t, "test"),
+			paths: nil,
+			want:  map[string][]string{},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		defer tt.dir.Remove()
+
+		t.Run(tt.name, func(_ *testing.T) {
+			root, err := newVaultPath(tt.dir.Path())
+			r.NoError(err)
+
+			// the result does not depend on the number of concurrent reads
+			for _, jobs := range []int{1, 4, 0} {
+				result := maps.Collect(collectTags(context.Background(), os.DirFS(root.String()), tt.paths, jobs))
+				r.Equal(tt.want, result)
+			}
+
+			// stopping early does not block the pipeline
+			for range collectTags(context.Background(), os.DirFS(root.String()), tt.paths, 1) {
+				break
+			}
+		})
+	}
+}
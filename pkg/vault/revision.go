@@ -0,0 +1,335 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// OpenRevision returns a Scanner for the vault at the directory path as of the
+// git revision rev, such as a commit hash, a branch, a tag, or HEAD~3. The vault
+// may be the root or any subdirectory of a git repository. Notes, ignore files
+// and the ExcludeFile are read straight from the tree of the commit, so nothing
+// is checked out and the working tree is left untouched.
+//
+// The vault is read-only, like an archive: it is never cached, and notes cannot
+// be written.
+func OpenRevision(path, rev string, opts ...Option) (*Scanner, error) {
+	root, err := newVaultPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, dir, err := openRepo(root)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := resolveCommit(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err := newTreeFS(commit, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s at %s: %w", root, rev, err)
+	}
+
+	return NewFSScanner(fsys, root.String()+"@"+rev, opts...), nil
+}
+
+// openRepo opens the git repository holding the vault at root, and returns the
+// slash-separated path of the vault relative to the top of the repository.
+func openRepo(root vaultPath) (*git.Repository, string, error) {
+	repo, err := git.PlainOpenWithOptions(root.String(), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", root, err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", root, err)
+	}
+
+	// resolve symlinks on both sides, as in /tmp on macOS
+	top, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return nil, "", err
+	}
+	abs, err := filepath.EvalSymlinks(root.String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	rel, err := filepath.Rel(top, abs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return repo, filepath.ToSlash(rel), nil
+}
+
+// resolveCommit returns the commit rev refers to. Annotated tags are peeled to
+// the commit they point to.
+func resolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	h, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+
+	return repo.CommitObject(*h)
+}
+
+// treeFS is a read-only fs.FS over a git tree object. All files share the
+// time of the commit as modification time, and the hash of their blob as
+// FileInfo.Sys.
+//
+// Trees of go-git are not safe for concurrent use, so every access goes
+// through mu, and files are read into memory when opened.
+type treeFS struct {
+	mu      sync.Mutex
+	tree    *object.Tree
+	modTime time.Time
+}
+
+// newTreeFS returns an fs.FS over the directory dir, slash-separated and
+// relative to the top of the tree of commit.
+func newTreeFS(commit *object.Commit, dir string) (*treeFS, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	if dir != "." {
+		if tree, err = tree.Tree(dir); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, fs.ErrNotExist)
+		}
+	}
+
+	return &treeFS{tree: tree, modTime: commit.Committer.When}, nil
+}
+
+// Open implements fs.FS.
+func (t *treeFS) Open(name string) (fs.File, error) {
+	info, err := t.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.IsDir() {
+		entries, err := t.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &treeDir{info: info, entries: entries}, nil
+	}
+
+	data, err := t.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &treeFile{info: info, Reader: bytes.NewReader(data)}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (t *treeFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := t.tree.File(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: treeErr(err)}
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tree := t.tree
+	if name != "." {
+		sub, err := t.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: treeErr(err)}
+		}
+		tree = sub
+	}
+
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		info := treeInfo{
+			name:    e.Name,
+			mode:    fileMode(e.Mode),
+			modTime: t.modTime,
+			hash:    e.Hash,
+		}
+		if info.mode.IsRegular() {
+			size, err := tree.Size(e.Name)
+			if err != nil {
+				return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+			}
+			info.size = size
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+
+	// git sorts directories as if their name ended with a slash
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// Stat implements fs.StatFS, without reading the content of files.
+func (t *treeFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := t.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// stat returns the FileInfo of the file or directory at name.
+func (t *treeFS) stat(name string) (treeInfo, error) {
+	if !fs.ValidPath(name) {
+		return treeInfo{}, fs.ErrInvalid
+	}
+	if name == "." {
+		return treeInfo{name: ".", mode: fs.ModeDir | 0o755, modTime: t.modTime, hash: t.tree.Hash}, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, err := t.tree.FindEntry(name)
+	if err != nil {
+		return treeInfo{}, treeErr(err)
+	}
+
+	info := treeInfo{
+		name:    path.Base(name),
+		mode:    fileMode(e.Mode),
+		modTime: t.modTime,
+		hash:    e.Hash,
+	}
+	if info.mode.IsRegular() {
+		if info.size, err = t.tree.Size(name); err != nil {
+			return treeInfo{}, err
+		}
+	}
+
+	return info, nil
+}
+
+// treeErr maps the lookup errors of go-git to fs.ErrNotExist.
+func treeErr(err error) error {
+	switch {
+	case errors.Is(err, object.ErrFileNotFound),
+		errors.Is(err, object.ErrDirectoryNotFound),
+		errors.Is(err, object.ErrEntryNotFound):
+		return fs.ErrNotExist
+	}
+	return err
+}
+
+// fileMode converts a git file mode to an fs.FileMode. Submodules are
+// reported as irregular files, since their content is not part of the tree.
+func fileMode(m filemode.FileMode) fs.FileMode {
+	switch m {
+	case filemode.Dir:
+		return fs.ModeDir | 0o755
+	case filemode.Executable:
+		return 0o755
+	case filemode.Symlink:
+		return fs.ModeSymlink | 0o777
+	case filemode.Submodule:
+		return fs.ModeIrregular
+	}
+	return 0o644
+}
+
+// treeInfo implements fs.FileInfo for the entries of a treeFS.
+type treeInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	hash    plumbing.Hash
+}
+
+func (i treeInfo) Name() string       { return i.name }
+func (i treeInfo) Size() int64        { return i.size }
+func (i treeInfo) Mode() fs.FileMode  { return i.mode }
+func (i treeInfo) ModTime() time.Time { return i.modTime }
+func (i treeInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i treeInfo) Sys() any           { return i.hash }
+
+// treeFile is a file of a treeFS, read into memory.
+type treeFile struct {
+	info treeInfo
+	*bytes.Reader
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *treeFile) Close() error               { return nil }
+
+// treeDir is a directory of a treeFS.
+type treeDir struct {
+	info    treeInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepo is a git repository in a temporary directory.
+type gitRepo struct {
+	t    *testing.T
+	repo *git.Repository
+	dir  string
+	when time.Time
+}
+
+func newGitRepo(t *testing.T) *gitRepo {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	return &gitRepo{
+		t:    t,
+		repo: repo,
+		dir:  dir,
+		when: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// commit writes files to the working tree, removing those with empty
+// content, and commits all changes. Commits are a day apart.
+func (g *gitRepo) commit(msg string, files map[string]string) {
+	r := require.New(g.t)
+
+	for name, content := range files {
+		p := filepath.Join(g.dir, filepath.FromSlash(name))
+		if content == "" {
+			r.NoError(os.Remove(p))
+			continue
+		}
+		r.NoError(os.MkdirAll(filepath.Dir(p), 0o755))
+		r.NoError(os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	wt, err := g.repo.Worktree()
+	r.NoError(err)
+	r.NoError(wt.AddWithOptions(&git.AddOptions{All: true}))
+
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: g.when}
+	_, err = wt.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig})
+	r.NoError(err)
+
+	g.when = g.when.AddDate(0, 0, 1)
+}
+
+func TestOpenRevision(t *testing.T) {
+	g := newGitRepo(t)
+	g.commit("initial", map[string]string{
+		"note1.md":       "#golang #daily",
+		"notes/note2.md": "#cobra",
+		"notes/draft.md": "#draft",
+		".gitignore":     "notes/draft.md\n",
+	})
+	g.commit("reorganize", map[string]string{
+		"note1.md":       "#go",
+		"notes/note3.md": "#rust",
+		".gitignore":     "",
+		ExcludeFile:      "daily",
+	})
+
+	// uncommitted changes are never seen
+	require.NoError(t, os.WriteFile(filepath.Join(g.dir, "note4.md"), []byte("#wip"), 0o644))
+
+	testCases := []struct {
+		name string
+		path string
+		rev  string
+		want map[string]int
+	}{
+		{
+			name: "head",
+			path: g.dir,
+			rev:  "HEAD",
+			want: map[string]int{"go": 1, "cobra": 1, "rust": 1, "draft": 1},
+		},
+		{
+			name: "previous commit with its ignore files",
+			path: g.dir,
+			rev:  "HEAD~1",
+			want: map[string]int{"golang": 1, "daily": 1, "cobra": 1},
+		},
+		{
+			name: "branch",
+			path: g.dir,
+			rev:  "master",
+			want: map[string]int{"go": 1, "cobra": 1, "rust": 1, "draft": 1},
+		},
+		{
+			name: "subdirectory",
+			path: filepath.Join(g.dir, "notes"),
+			rev:  "HEAD~1",
+			want: map[string]int{"cobra": 1},
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			s, err := OpenRevision(tt.path, tt.rev, WithExcludeFile())
+			r.NoError(err)
+			defer s.Close()
+
+			res, err := s.Scan(context.Background())
+			r.NoError(err)
+			r.Equal(tt.want, res.Counts)
+			r.Equal(tt.path+"@"+tt.rev, res.Root)
+
+			r.ErrorIs(s.WriteNote("note1.md", "#go"), ErrReadOnly)
+		})
+	}
+
+	r.NoFileExists(filepath.Join(g.dir, CacheFile))
+}
+
+func TestOpenRevision_ErrorCases(t *testing.T) {
+	g := newGitRepo(t)
+	g.commit("initial", map[string]string{"note.md": "#golang"})
+	require.NoError(t, os.Mkdir(filepath.Join(g.dir, "new"), 0o755))
+
+	testCases := []struct {
+		name string
+		path string
+		rev  string
+		err  error
+	}{
+		{name: "not a repository", path: t.TempDir(), rev: "HEAD", err: git.ErrRepositoryNotExists},
+		{name: "unknown revision", path: g.dir, rev: "HEAD~5"},
+		{name: "vault not in revision", path: filepath.Join(g.dir, "new"), rev: "HEAD", err: fs.ErrNotExist},
+		{name: "missing vault", path: filepath.Join(g.dir, "missing"), rev: "HEAD", err: fs.ErrNotExist},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			_, err := OpenRevision(tt.path, tt.rev)
+			r.Error(err)
+			if tt.err != nil {
+				r.ErrorIs(err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_treeFS(t *testing.T) {
+	r := require.New(t)
+
+	g := newGitRepo(t)
+	g.commit("initial", map[string]string{
+		"note.md":        "#golang",
+		"a/b/nested.md":  "#nested",
+		"a/attachment":   "data",
+		"z.md":           "#z",
+		"a.md/inside.md": "#inside",
+	})
+
+	head, err := g.repo.Head()
+	r.NoError(err)
+	commit, err := g.repo.CommitObject(head.Hash())
+	r.NoError(err)
+
+	fsys, err := newTreeFS(commit, ".")
+	r.NoError(err)
+	r.NoError(fstest.TestFS(fsys, "note.md", "a/b/nested.md", "a/attachment", "z.md", "a.md/inside.md"))
+
+	info, err := fs.Stat(fsys, "a/b/nested.md")
+	r.NoError(err)
+	r.Equal(int64(len("#nested")), info.Size())
+	r.True(info.ModTime().Equal(commit.Committer.When))
+}
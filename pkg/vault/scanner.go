@@ -0,0 +1,383 @@
+// Package vault scans Obsidian vaults for tags.
+//
+// A Scanner walks a vault, extracts the tags of every note with package tagx,
+// and aggregates them. Vaults are read through an fs.FS, so that they can be
+// scanned from a directory, an archive, or an in-memory tree. Tags of vaults on
+// disk are cached per note in the vault, so that only notes added or changed
+// since the last scan are read again.
+package vault
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+)
+
+const (
+	// CacheFile is the name of the cache file at the root of a vault.
+	CacheFile = ".tobi.json"
+	// ExcludeFile is the name of the file holding tag exclude patterns at the
+	// root of a vault. See tagx.NewTagGlobs.
+	ExcludeFile = ".tobi.exclude"
+)
+
+// CachePolicy controls how a Scanner uses the cache file of a vault.
+type CachePolicy int
+
+const (
+	// CacheReadWrite reads the cache, rescans only added or changed notes, and
+	// writes the cache back if it changed. This is the default.
+	CacheReadWrite CachePolicy = iota
+	// CacheRebuild ignores the existing cache, scans every note, and writes a
+	// new cache.
+	CacheRebuild
+	// CacheDisabled scans every note and never reads or writes the cache.
+	CacheDisabled
+)
+
+// ErrReadOnly is returned when writing to a vault that is not a directory on
+// disk, such as an archive.
+var ErrReadOnly = errors.New("vault is read-only")
+
+// Scanner scans the notes of a vault for tags. Use NewScanner, NewFSScanner or
+// Open to create one.
+type Scanner struct {
+	fsys fs.FS
+	// name is the path of the vault, or of the archive holding it.
+	name string
+	// root is the directory of the vault on disk, or empty if the vault is
+	// read-only.
+	root   vaultPath
+	closer io.Closer
+
+	version     string
+	ignoreRules bool
+	exclude     func(tag string) bool
+	excludeFile bool
+	cache       CachePolicy
+	jobs        int
+}
+
+// Option configures a Scanner.
+type Option func(*Scanner)
+
+// WithIgnoreRules sets whether notes ignored by the .gitignore and .tobiignore
+// files of the vault are skipped. Defaults to true.
+func WithIgnoreRules(enabled bool) Option {
+	return func(s *Scanner) {
+		s.ignoreRules = enabled
+	}
+}
+
+// WithExcludes drops tags matching any of the globs from the scan result.
+// Excludes never affect the cache, so changing them does not require a rescan.
+func WithExcludes(globs tagx.TagGlobs) Option {
+	return func(s *Scanner) {
+		s.exclude = globs.Match
+	}
+}
+
+// WithExcludeFile drops tags matching any of the globs in the ExcludeFile of
+// the vault from the scan result, in addition to those set with WithExcludes.
+// The file is read again on every scan.
+func WithExcludeFile() Option {
+	return func(s *Scanner) {
+		s.excludeFile = true
+	}
+}
+
+// WithCachePolicy sets how the cache file of the vault is used. Defaults to
+// CacheReadWrite. Read-only vaults are never cached.
+func WithCachePolicy(p CachePolicy) Option {
+	return func(s *Scanner) {
+		s.cache = p
+	}
+}
+
+// WithJobs sets the maximum number of notes read concurrently. Non-positive
+// values, the default, mean GOMAXPROCS.
+func WithJobs(n int) Option {
+	return func(s *Scanner) {
+		s.jobs = n
+	}
+}
+
+// WithVersion sets the version of the program using the scanner. Caches
+// written by another version are discarded, as tag extraction rules may differ
+// between versions.
+func WithVersion(v string) Option {
+	return func(s *Scanner) {
+		s.version = v
+	}
+}
+
+// NewScanner returns a Scanner for the vault at the directory root.
+//
+// Returns an error if root is not a directory.
+func NewScanner(root string, opts ...Option) (*Scanner, error) {
+	p, err := newVaultPath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newScanner(os.DirFS(p.String()), p.String(), opts)
+	s.root = p
+	return s, nil
+}
+
+// NewFSScanner returns a Scanner for the vault at the root of fsys. name
+// identifies the vault in scan results, e.g. the path of an archive. The vault
+// is read-only: it is never cached, and notes cannot be written.
+func NewFSScanner(fsys fs.FS, name string, opts ...Option) *Scanner {
+	return newScanner(fsys, name, opts)
+}
+
+// Open returns a Scanner for the vault at path, which is either a directory
+// or an archive of a vault. See OpenArchive for the supported archives.
+//
+// The Scanner must be closed to release the archive.
+func Open(path string, opts ...Option) (*Scanner, error) {
+	if !IsArchive(path) {
+		return NewScanner(path, opts...)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, closer, err := OpenArchive(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewFSScanner(fsys, abs, opts...)
+	s.closer = closer
+	return s, nil
+}
+
+func newScanner(fsys fs.FS, name string, opts []Option) *Scanner {
+	s := &Scanner{
+		fsys:        fsys,
+		name:        name,
+		ignoreRules: true,
+		exclude:     func(string) bool { return false },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Close releases the resources held by the scanner, such as an open archive.
+func (s *Scanner) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// Root returns the path of the vault, or of the archive holding it.
+func (s *Scanner) Root() string {
+	return s.name
+}
+
+// ReadNote returns the content of the note at the slash-separated,
+// vault-relative path rel.
+func (s *Scanner) ReadNote(rel string) (string, error) {
+	b, err := fs.ReadFile(s.fsys, rel)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// cachePolicy returns the cache policy in effect, which is always
+// CacheDisabled for read-only vaults.
+func (s *Scanner) cachePolicy() CachePolicy {
+	if s.root == "" {
+		return CacheDisabled
+	}
+	return s.cache
+}
+
+// Note is a note of a vault along with its tags.
+type Note struct {
+	// Path is the slash-separated, vault-relative path of the note.
+	Path string
+	// Tags are the tags of the note in order of appearance, including
+	// duplicates.
+	Tags []string
+}
+
+// Result is the result of a scan.
+type Result struct {
+	// Root is the absolute path of the vault.
+	Root string
+	// Notes are all the notes of the vault, sorted by path.
+	Notes []Note
+	// Counts is the number of occurrences of every tag across all notes.
+	Counts map[string]int
+	// Total is the number of tag occurrences across all notes.
+	Total int
+	// CacheHit is true if every note was served from the cache.
+	CacheHit bool
+}
+
+// Scan lists the notes of the vault and extracts their tags, reading only notes
+// that were added or changed since the last scan, depending on the cache
+// policy. Excluded tags are dropped from the result.
+//
+// Failing to write the cache is not a fatal error and is only logged.
+//
+// Returns an error if the vault cannot be walked, or if ctx is done before the
+// scan completes.
+func (s *Scanner) Scan(ctx context.Context) (*Result, error) {
+	exclude, err := s.excluder()
+	if err != nil {
+		return nil, err
+	}
+
+	c, cacheHit, err := s.scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.result(c, exclude, cacheHit), nil
+}
+
+// scan lists the notes of the vault and returns their unfiltered tags, read
+// from the cache or from the notes depending on the cache policy, and whether
+// every note was served from the cache.
+func (s *Scanner) scan(ctx context.Context) (c noteCache, cacheHit bool, err error) {
+	ns, err := listNotes(ctx, s.fsys, s.ignoreRules)
+	if err != nil {
+		return noteCache{}, false, err
+	}
+
+	policy := s.cachePolicy()
+	c = newNoteCache(s.version)
+	cached := false
+	if policy == CacheReadWrite {
+		// a stale, corrupted, or missing cache is rebuilt from scratch
+		if cc, err := readCache(s.root, s.version); err == nil {
+			c, cached = cc, true
+		}
+	}
+
+	modified := c.refresh(ctx, ns, s.jobs)
+	if err := ctx.Err(); err != nil {
+		return noteCache{}, false, err
+	}
+
+	if policy == CacheRebuild || (policy == CacheReadWrite && modified) {
+		s.writeCache(c)
+	}
+
+	return c, cached && !modified, nil
+}
+
+// writeCache writes c to the cache file of the vault. Failing to write the
+// cache is not a fatal error and is only logged.
+func (s *Scanner) writeCache(c noteCache) {
+	if err := c.write(s.root); err != nil {
+		log.Printf("failed to write cache to %s: %v", s.root.cachePath(), err)
+	}
+}
+
+// excluder returns a function reporting whether a tag is excluded, either by
+// WithExcludes or, with WithExcludeFile, by the ExcludeFile of the vault.
+func (s *Scanner) excluder() (func(string) bool, error) {
+	if !s.excludeFile {
+		return s.exclude, nil
+	}
+
+	globs, err := tagx.NewTagGlobsFS(s.fsys, ExcludeFile)
+	if err != nil {
+		return nil, err
+	}
+	return func(tag string) bool {
+		return s.exclude(tag) || globs.Match(tag)
+	}, nil
+}
+
+// result aggregates the cached tags of all notes, dropping tags matching
+// exclude.
+func (s *Scanner) result(c noteCache, exclude func(string) bool, cacheHit bool) *Result {
+	res := &Result{
+		Root:     s.name,
+		Notes:    make([]Note, 0, len(c.Notes)),
+		Counts:   make(map[string]int),
+		CacheHit: cacheHit,
+	}
+
+	for p, e := range c.Notes {
+		var tags []string
+		for _, t := range e.Tags {
+			if exclude(t) {
+				continue
+			}
+			tags = append(tags, t)
+			res.Counts[t]++
+			res.Total++
+		}
+		res.Notes = append(res.Notes, Note{Path: p, Tags: tags})
+	}
+
+	slices.SortFunc(res.Notes, func(a, b Note) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return res
+}
+
+// ReadCacheFile returns the tags recorded in the cache file at path, such as a
+// copy of the CacheFile of a vault kept as a snapshot, without reading any
+// note. Unlike Scan, caches written by other versions of tobi are accepted.
+// Tags are filtered with the excludes set with WithExcludes; other options are
+// ignored.
+//
+// Returns an error if the file cannot be read or decoded, or if it was written
+// with an unsupported cache format.
+func ReadCacheFile(path string, opts ...Option) (*Result, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := readCacheFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newScanner(nil, abs, opts)
+	return s.result(c, s.exclude, true), nil
+}
+
+// Update brings the cache up to date with notes that were rewritten since the
+// last scan, without walking the vault again. notes maps the vault-relative path
+// of every rewritten note to its new content, which must have been written to
+// disk.
+//
+// Update does nothing if the cache is disabled. Returns an error if the cache
+// cannot be read or written, or if a note cannot be found.
+func (s *Scanner) Update(notes map[string]string) error {
+	if s.cachePolicy() == CacheDisabled || len(notes) == 0 {
+		return nil
+	}
+
+	c, err := readCache(s.root, s.version)
+	if err != nil {
+		return err
+	}
+
+	return c.update(s.root, notes)
+}
@@ -0,0 +1,271 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nt54hamnghi/tobi/pkg/tagx"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func TestScanner_result(t *testing.T) {
+	noIgnore := func(string) bool {
+		return false
+	}
+
+	c := noteCache{
+		Notes: map[string]cacheEntry{
+			"note2.md": {Tags: []string{"golang", "cli"}},
+			"note1.md": {Tags: []string{"golang", "cobra", "daily"}},
+			"note3.md": {Tags: nil},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		cache     noteCache
+		filter    func(string) bool
+		want      map[string]int
+		wantNotes []Note
+		wantTotal int
+	}{
+		{
+			name:   "multiple notes",
+			cache:  c,
+			filter: noIgnore,
+			want: map[string]int{
+				"golang": 2,
+				"cobra":  1,
+				"cli":    1,
+				"daily":  1,
+			},
+			wantNotes: []Note{
+				{Path: "note1.md", Tags: []string{"golang", "cobra", "daily"}},
+				{Path: "note2.md", Tags: []string{"golang", "cli"}},
+				{Path: "note3.md"},
+			},
+			wantTotal: 5,
+		},
+		{
+			name:  "with filter",
+			cache: c,
+			filter: func(s string) bool {
+				return s == "daily"
+			},
+			want: map[string]int{
+				"golang": 2,
+				"cobra":  1,
+				"cli":    1,
+			},
+			wantNotes: []Note{
+				{Path: "note1.md", Tags: []string{"golang", "cobra"}},
+				{Path: "note2.md", Tags: []string{"golang", "cli"}},
+				{Path: "note3.md"},
+			},
+			wantTotal: 4,
+		},
+		{
+			name:  "ignore all tags",
+			cache: c,
+			filter: func(string) bool {
+				return true
+			},
+			want: map[string]int{},
+			wantNotes: []Note{
+				{Path: "note1.md"},
+				{Path: "note2.md"},
+				{Path: "note3.md"},
+			},
+			wantTotal: 0,
+		},
+		{
+			name:      "empty cache",
+			cache:     newNoteCache("test"),
+			filter:    noIgnore,
+			want:      map[string]int{},
+			wantNotes: []Note{},
+			wantTotal: 0,
+		},
+	}
+
+	r := require.New(t)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			s := &Scanner{name: "/vault"}
+			result := s.result(tt.cache, tt.filter, true)
+
+			r.Equal("/vault", result.Root)
+			r.Equal(tt.want, result.Counts)
+			r.Equal(tt.wantNotes, result.Notes)
+			r.Equal(tt.wantTotal, result.Total)
+			r.True(result.CacheHit)
+		})
+	}
+}
+
+func TestScanner_Scan(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test",
+		fs.WithFiles(map[string]string{
+			"note1.md":      "#golang #daily",
+			"note2.md":      "#cobra",
+			"ignored.md":    "#secret",
+			".gitignore":    "ignored.md",
+			".tobi.exclude": "daily",
+		}),
+	)
+	defer dir.Remove()
+
+	excludes, err := tagx.NewTagGlobs(dir.Join(ExcludeFile))
+	r.NoError(err)
+
+	ctx := context.Background()
+	newScanner := func(opts ...Option) *Scanner {
+		s, err := NewScanner(dir.Path(), append([]Option{WithVersion("test"), WithJobs(2)}, opts...)...)
+		r.NoError(err)
+		return s
+	}
+
+	// the first scan reads every note and writes the cache
+	res, err := newScanner(WithExcludes(excludes)).Scan(ctx)
+	r.NoError(err)
+	r.Equal(dir.Path(), res.Root)
+	r.Equal(map[string]int{"golang": 1, "cobra": 1}, res.Counts)
+	r.Equal(2, res.Total)
+	r.Len(res.Notes, 2)
+	r.False(res.CacheHit)
+	r.FileExists(dir.Join(CacheFile))
+
+	// the second scan is served from the cache
+	res, err = newScanner().Scan(ctx)
+	r.NoError(err)
+	r.Equal(map[string]int{"golang": 1, "daily": 1, "cobra": 1}, res.Counts)
+	r.True(res.CacheHit)
+
+	// rebuilding the cache reads every note again
+	res, err = newScanner(WithCachePolicy(CacheRebuild)).Scan(ctx)
+	r.NoError(err)
+	r.False(res.CacheHit)
+
+	// ignore rules can be disabled
+	res, err = newScanner(WithIgnoreRules(false), WithCachePolicy(CacheDisabled)).Scan(ctx)
+	r.NoError(err)
+	r.Len(res.Notes, 3)
+	r.Equal(1, res.Counts["secret"])
+	r.False(res.CacheHit)
+
+	// a disabled cache is never written
+	r.NoError(os.Remove(dir.Join(CacheFile)))
+	_, err = newScanner(WithCachePolicy(CacheDisabled)).Scan(ctx)
+	r.NoError(err)
+	r.NoFileExists(dir.Join(CacheFile))
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = newScanner().Scan(cctx)
+	r.ErrorIs(err, context.Canceled)
+}
+
+func TestNewFSScanner(t *testing.T) {
+	r := require.New(t)
+
+	fsys := fstest.MapFS{
+		"note1.md":          {Data: []byte("#golang #daily")},
+		"sub/note2.md":      {Data: []byte("#cobra")},
+		"sub/ignored.md":    {Data: []byte("#secret")},
+		"sub/.gitignore":    {Data: []byte("ignored.md")},
+		ExcludeFile:         {Data: []byte("daily")},
+		"attachments/a.png": {Data: []byte("#png")},
+	}
+
+	s := NewFSScanner(fsys, "vault.zip", WithExcludeFile(), WithJobs(2))
+	defer s.Close()
+
+	res, err := s.Scan(context.Background())
+	r.NoError(err)
+	r.Equal("vault.zip", res.Root)
+	r.Equal(map[string]int{"golang": 1, "cobra": 1}, res.Counts)
+	r.Equal([]Note{
+		{Path: "note1.md", Tags: []string{"golang"}},
+		{Path: "sub/note2.md", Tags: []string{"cobra"}},
+	}, res.Notes)
+	r.False(res.CacheHit)
+
+	got, err := s.ReadNote("sub/note2.md")
+	r.NoError(err)
+	r.Equal("#cobra", got)
+
+	// read-only vaults are never written
+	r.ErrorIs(s.WriteNote("note1.md", "#go"), ErrReadOnly)
+	r.NoError(s.Update(map[string]string{"note1.md": "#go"}))
+	r.NotContains(fsys, CacheFile)
+}
+
+func TestScanner_Update(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("note.md", "#golang"))
+	defer dir.Remove()
+
+	s, err := NewScanner(dir.Path(), WithVersion("test"))
+	r.NoError(err)
+
+	ctx := context.Background()
+	_, err = s.Scan(ctx)
+	r.NoError(err)
+
+	r.NoError(os.WriteFile(dir.Join("note.md"), []byte("#go"), 0o644))
+	r.NoError(s.Update(map[string]string{"note.md": "#go"}))
+
+	res, err := s.Scan(ctx)
+	r.NoError(err)
+	r.True(res.CacheHit)
+	r.Equal(map[string]int{"go": 1}, res.Counts)
+}
+
+func TestReadCacheFile(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("note.md", "#golang #daily"))
+	defer dir.Remove()
+
+	s, err := NewScanner(dir.Path(), WithVersion("old"))
+	r.NoError(err)
+	_, err = s.Scan(context.Background())
+	r.NoError(err)
+
+	excludes, err := tagx.NewTagGlobs(dir.Join("missing"))
+	r.NoError(err)
+
+	// caches written by other versions are accepted
+	res, err := ReadCacheFile(dir.Join(CacheFile), WithExcludes(excludes), WithVersion("new"))
+	r.NoError(err)
+	r.Equal(dir.Join(CacheFile), res.Root)
+	r.Equal(map[string]int{"golang": 1, "daily": 1}, res.Counts)
+	r.Equal([]Note{{Path: "note.md", Tags: []string{"golang", "daily"}}}, res.Notes)
+
+	r.NoError(os.WriteFile(dir.Join("old.json"), []byte(`{"golang": 1}`), 0o644))
+	_, err = ReadCacheFile(dir.Join("old.json"))
+	r.ErrorContains(err, "unsupported cache version")
+
+	_, err = ReadCacheFile(dir.Join("missing.json"))
+	r.Error(err)
+}
+
+func TestNewScanner_ErrorCases(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("note.md", "#golang"))
+	defer dir.Remove()
+
+	_, err := NewScanner(dir.Join("missing"))
+	r.Error(err)
+
+	_, err = NewScanner(dir.Join("note.md"))
+	r.Error(err)
+}
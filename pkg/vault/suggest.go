@@ -0,0 +1,188 @@
+package vault
+
+import (
+	"cmp"
+	"context"
+	"io/fs"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// cooccurrenceWeight is the share of the score of a suggestion given to its
+// co-occurrence with the tags of the text, when the text has tags.
+const cooccurrenceWeight = 0.3
+
+// Suggestion is an existing tag suggested for a text.
+type Suggestion struct {
+	Tag string
+	// Score ranks suggestions, from 0 to 1. It is Similarity for texts without
+	// tags, and a weighted sum of Similarity and Cooccurrence otherwise.
+	Score float64
+	// Similarity is the cosine similarity between the TF-IDF vectors of the
+	// text and of the notes carrying the tag, taken together, from 0 to 1.
+	Similarity float64
+	// Cooccurrence is the highest Jaccard index of the tag with any tag of the
+	// text, from 0 to 1.
+	Cooccurrence float64
+}
+
+// Suggester ranks the tags of a vault for arbitrary texts, using only the
+// notes of the vault. Use Scanner.Suggester to create one.
+type Suggester struct {
+	// docs is the number of notes read.
+	docs int
+	// df is the number of notes containing every term.
+	df map[string]int
+	// tags are the normalized TF-IDF vectors of every tag.
+	tags map[string]map[string]float64
+	co   *Cooccurrence
+}
+
+// Suggester reads notes, as found in Result.Notes, and returns a Suggester
+// for their tags. Every tag carried by notes is described by the text of all
+// the notes carrying it. Notes are read concurrently, at most as many at once
+// as set with WithJobs. Notes that cannot be read are logged and skipped.
+//
+// Returns an error if ctx is done before every note is read.
+func (s *Scanner) Suggester(ctx context.Context, notes []Note) (*Suggester, error) {
+	sg := &Suggester{
+		df:   make(map[string]int),
+		tags: make(map[string]map[string]float64),
+		co:   NewCooccurrence(notes),
+	}
+
+	paths := make([]string, len(notes))
+	tagsOf := make(map[string][]string, len(notes))
+	for i, n := range notes {
+		paths[i] = n.Path
+		tags := slices.Clone(n.Tags)
+		slices.Sort(tags)
+		tagsOf[n.Path] = slices.Compact(tags)
+	}
+
+	// term counts of the text of every tag
+	tf := make(map[string]map[string]int)
+	for p, terms := range mapNotes(ctx, s.fsys, paths, s.jobs, readTerms) {
+		sg.docs++
+		for t := range terms {
+			sg.df[t]++
+		}
+
+		for _, tag := range tagsOf[p] {
+			if tf[tag] == nil {
+				tf[tag] = make(map[string]int)
+			}
+			for t, c := range terms {
+				tf[tag][t] += c
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for tag, terms := range tf {
+		sg.tags[tag] = sg.vector(terms)
+	}
+
+	return sg, nil
+}
+
+// readTerms reads the note at the vault-relative path n and counts its terms.
+// Errors are logged, and ok is false.
+func readTerms(fsys fs.FS, n string) (terms map[string]int, ok bool) {
+	f, err := fs.ReadFile(fsys, n)
+	if err != nil {
+		log.Printf("failed to open file %s: %v", n, err)
+		return nil, false
+	}
+	return termCounts(string(f)), true
+}
+
+// Suggest returns the tags of the vault for text, sorted by score in
+// descending order, then by name. tags are the tags text already has, which
+// are never suggested. Tags with a score of 0 are dropped, and at most n tags
+// are returned; non-positive values of n mean all.
+func (sg *Suggester) Suggest(text string, tags []string, n int) []Suggestion {
+	q := sg.vector(termCounts(text))
+	// sums are taken in a fixed order, so that scores do not depend on the
+	// order of iteration over maps
+	terms := slices.Sorted(maps.Keys(q))
+
+	co := make(map[string]float64)
+	for _, tag := range tags {
+		for _, p := range sg.co.Related(tag, 1) {
+			co[p.B] = max(co[p.B], p.Jaccard)
+		}
+	}
+
+	suggestions := []Suggestion{}
+	for tag, v := range sg.tags {
+		if slices.Contains(tags, tag) {
+			continue
+		}
+
+		var sim float64
+		for _, t := range terms {
+			sim += q[t] * v[t]
+		}
+
+		s := Suggestion{Tag: tag, Similarity: sim, Cooccurrence: co[tag], Score: sim}
+		if len(tags) > 0 {
+			s.Score = (1-cooccurrenceWeight)*sim + cooccurrenceWeight*s.Cooccurrence
+		}
+		if s.Score > 0 {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Tag, b.Tag))
+	})
+	if n > 0 {
+		suggestions = suggestions[:min(len(suggestions), n)]
+	}
+	return suggestions
+}
+
+// vector returns the TF-IDF vector of terms, with a unit length. Term
+// frequencies are dampened logarithmically, and inverse document frequencies
+// are smoothed so that terms missing from the vault keep a positive weight.
+func (sg *Suggester) vector(terms map[string]int) map[string]float64 {
+	v := make(map[string]float64, len(terms))
+	var norm float64
+	for _, t := range slices.Sorted(maps.Keys(terms)) {
+		c := terms[t]
+		idf := math.Log(float64(1+sg.docs)/float64(1+sg.df[t])) + 1
+		w := (1 + math.Log(float64(c))) * idf
+		v[t] = w
+		norm += w * w
+	}
+
+	norm = math.Sqrt(norm)
+	for t := range v {
+		v[t] /= norm
+	}
+	return v
+}
+
+// termCounts returns the number of occurrences of every term of text. Terms are
+// lowercased runs of letters and digits of at least two runes, except numbers.
+func termCounts(text string) map[string]int {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make(map[string]int)
+	for _, w := range words {
+		if len([]rune(w)) < 2 || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		terms[w]++
+	}
+	return terms
+}
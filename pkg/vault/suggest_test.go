@@ -0,0 +1,131 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner_Suggester(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.md":     {Data: []byte("Goroutines and channels make concurrency simple. #golang")},
+		"cobra.md":  {Data: []byte("Cobra commands, flags and completions for a CLI. #golang #cli")},
+		"clap.md":   {Data: []byte("Clap parses flags and subcommands of a CLI. #rust #cli")},
+		"borrow.md": {Data: []byte("The borrow checker and lifetimes. #rust")},
+		"empty.md":  {Data: []byte("Nothing to see here.")},
+	}
+
+	r := require.New(t)
+
+	s := NewFSScanner(fsys, "vault")
+	res, err := s.Scan(t.Context())
+	r.NoError(err)
+
+	sg, err := s.Suggester(t.Context(), res.Notes)
+	r.NoError(err)
+
+	// tags returns the tags of every suggestion
+	tags := func(suggestions []Suggestion) []string {
+		out := []string{}
+		for _, s := range suggestions {
+			out = append(out, s.Tag)
+		}
+		return out
+	}
+
+	testCases := []struct {
+		name string
+		text string
+		tags []string
+		n    int
+		want []string
+	}{
+		{
+			name: "similar text",
+			text: "Channels between goroutines",
+			want: []string{"golang"},
+		},
+		{
+			name: "shared terms rank every tag",
+			text: "Flags of a CLI, with goroutines and channels",
+			want: []string{"golang", "cli", "rust"},
+		},
+		{
+			name: "limit",
+			text: "Flags of a CLI, with goroutines and channels",
+			n:    1,
+			want: []string{"golang"},
+		},
+		{
+			name: "existing tags are not suggested and rank co-occurring tags",
+			text: "Lifetimes of flags",
+			tags: []string{"rust"},
+			want: []string{"cli", "golang"},
+		},
+		{
+			name: "unknown terms",
+			text: "Sourdough bread recipe",
+			want: []string{},
+		},
+		{
+			name: "empty text",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			got := sg.Suggest(tt.text, tt.tags, tt.n)
+			r.Equal(tt.want, tags(got))
+			for _, s := range got {
+				r.InDelta(0.5, s.Score, 0.5)
+				r.InDelta(0.5, s.Similarity, 0.5)
+				r.InDelta(0.5, s.Cooccurrence, 0.5)
+			}
+		})
+	}
+}
+
+func TestSuggester_Suggest_Scores(t *testing.T) {
+	r := require.New(t)
+
+	fsys := fstest.MapFS{
+		"a.md": {Data: []byte("alpha beta #x #y")},
+		"b.md": {Data: []byte("alpha #y")},
+	}
+	s := NewFSScanner(fsys, "vault")
+	res, err := s.Scan(t.Context())
+	r.NoError(err)
+
+	sg, err := s.Suggester(t.Context(), res.Notes)
+	r.NoError(err)
+
+	// without tags, the score is the similarity
+	got := sg.Suggest("beta", nil, 0)
+	r.Len(got, 2)
+	r.Equal("x", got[0].Tag)
+	r.Equal(got[0].Similarity, got[0].Score)
+	r.Zero(got[0].Cooccurrence)
+
+	// with tags, co-occurrence is weighted in
+	got = sg.Suggest("beta", []string{"y"}, 0)
+	r.Len(got, 1)
+	r.Equal("x", got[0].Tag)
+	r.InDelta(0.5, got[0].Cooccurrence, 1e-9)
+	r.InDelta(0.7*got[0].Similarity+0.3*0.5, got[0].Score, 1e-9)
+}
+
+func TestScanner_Suggester_Canceled(t *testing.T) {
+	r := require.New(t)
+
+	s := NewFSScanner(fstest.MapFS{"a.md": {Data: []byte("alpha #x")}}, "vault", WithJobs(2))
+	res, err := s.Scan(t.Context())
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = s.Suggester(ctx, res.Notes)
+	r.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,241 @@
+package vault
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nt54hamnghi/tobi/pkg/gitignore"
+)
+
+// fsEvent is a change to a file or directory of a watched vault.
+type fsEvent struct {
+	// Name is the slash-separated, vault-relative path of the changed entry.
+	// "." means that changes may have been missed, e.g. because the event
+	// queue overflowed, and the whole vault must be listed again.
+	Name string
+	// Dir is true if the changed entry is a directory.
+	Dir bool
+}
+
+// notifier reports changes to the files of a vault on disk. It is backed by
+// inotify on Linux, and by polling elsewhere.
+type notifier interface {
+	Events() <-chan fsEvent
+	Errors() <-chan error
+	Close() error
+}
+
+// Watch scans the vault like Scan, then watches it for changes until ctx is
+// done. fn is called with the result of the initial scan, then with a new
+// result every time the tags of the vault may have changed.
+//
+// Changes are collected until no change happens for debounce, so that a burst
+// of writes, such as an editor saving several notes, triggers a single update.
+// Only changed notes are read again. Changes to .gitignore or .tobiignore files
+// list the notes of the vault again, and changes to the ExcludeFile reload the
+// tag excludes if WithExcludeFile is set. The cache is kept up to date
+// depending on the cache policy.
+//
+// Returns ErrReadOnly if the vault is not a directory on disk. Otherwise,
+// returns ctx.Err() when ctx is done, or the first error returned by fn or
+// met while watching.
+func (s *Scanner) Watch(ctx context.Context, debounce time.Duration, fn func(*Result) error) error {
+	if s.root == "" {
+		return ErrReadOnly
+	}
+
+	// watch before the initial scan, so that no change is missed in between
+	n, err := newNotifier(s.root.String())
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+
+	w := &watcher{s: s}
+	if err := w.init(ctx); err != nil {
+		return err
+	}
+	if err := fn(s.result(w.cache, w.exclude, w.cacheHit)); err != nil {
+		return err
+	}
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-n.Errors():
+			return err
+		case e := <-n.Events():
+			if !w.relevant(e) {
+				continue
+			}
+			pending[e.Name] = pending[e.Name] || e.Dir
+			timer.Reset(debounce)
+		case <-timer.C:
+			changed, err := w.apply(ctx, pending)
+			if err != nil {
+				return err
+			}
+			clear(pending)
+
+			if changed {
+				if err := fn(s.result(w.cache, w.exclude, false)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// watcher holds the state of a watched vault between changes.
+type watcher struct {
+	s        *Scanner
+	cache    noteCache
+	cacheHit bool
+	exclude  func(string) bool
+	// ignore is nil if ignore rules are disabled.
+	ignore *gitignore.FSMatcher
+}
+
+func (w *watcher) init(ctx context.Context) error {
+	var err error
+	if w.exclude, err = w.s.excluder(); err != nil {
+		return err
+	}
+	if err := w.loadIgnore(ctx); err != nil {
+		return err
+	}
+	w.cache, w.cacheHit, err = w.s.scan(ctx)
+	return err
+}
+
+func (w *watcher) loadIgnore(ctx context.Context) error {
+	if !w.s.ignoreRules {
+		return nil
+	}
+
+	m, err := gitignore.NewFSMatcher(ctx, w.s.fsys)
+	if err != nil {
+		return err
+	}
+	w.ignore = &m
+	return nil
+}
+
+// relevant reports whether e may change the tags of the vault. Changes to
+// files other than notes and rule files, such as attachments or the cache
+// file, are not.
+func (w *watcher) relevant(e fsEvent) bool {
+	switch {
+	case e.Name == infoExcludeFile:
+		return true
+	case e.Name == ".git" || strings.HasPrefix(e.Name, ".git/"):
+		return false
+	case e.Dir:
+		return true
+	}
+	return path.Ext(e.Name) == ".md" || e.Name == ExcludeFile || isIgnoreFile(e.Name)
+}
+
+// infoExcludeFile holds the gitignore patterns of the repository that are not
+// shared.
+const infoExcludeFile = ".git/info/exclude"
+
+func isIgnoreFile(name string) bool {
+	base := path.Base(name)
+	return base == ".gitignore" || base == ".tobiignore" || name == infoExcludeFile
+}
+
+// apply brings the watcher up to date with changes to the given entries,
+// mapped to whether they are directories. Returns true if the result of the
+// scan may have changed.
+func (w *watcher) apply(ctx context.Context, changes map[string]bool) (bool, error) {
+	relist := false
+	reexclude := false
+	var notes []string
+
+	for name, dir := range changes {
+		switch {
+		case name == ExcludeFile:
+			reexclude = w.s.excludeFile
+		case dir, isIgnoreFile(name):
+			// a directory may have been moved in or out of the vault
+			relist = true
+		default:
+			notes = append(notes, name)
+		}
+	}
+
+	modified := false
+	if relist {
+		if err := w.loadIgnore(ctx); err != nil {
+			return false, err
+		}
+
+		ns, err := listNotes(ctx, w.s.fsys, w.s.ignoreRules)
+		if err != nil {
+			return false, err
+		}
+		modified = w.cache.refresh(ctx, ns, w.s.jobs)
+	} else {
+		modified = w.update(ctx, notes)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if reexclude {
+		exclude, err := w.s.excluder()
+		if err != nil {
+			return false, err
+		}
+		w.exclude = exclude
+	}
+
+	if modified && w.s.cachePolicy() != CacheDisabled {
+		w.s.writeCache(w.cache)
+	}
+
+	return modified || reexclude, nil
+}
+
+// update reads the given notes again if they changed, and drops those that
+// were removed or are ignored. Unlike refresh, the vault is not listed again.
+// Returns true if the cache was modified.
+func (w *watcher) update(ctx context.Context, notes []string) bool {
+	modified := false
+	stats := make(map[string]noteStat)
+	var stale []string
+
+	for _, p := range notes {
+		info, err := fs.Stat(w.s.fsys, p)
+		if err != nil || !info.Mode().IsRegular() || (w.ignore != nil && w.ignore.MatchFile(p)) {
+			if _, ok := w.cache.Notes[p]; ok {
+				delete(w.cache.Notes, p)
+				modified = true
+			}
+			continue
+		}
+
+		st := newNoteStat(info)
+		if e, ok := w.cache.Notes[p]; ok && e.noteStat == st {
+			continue
+		}
+		stats[p] = st
+		stale = append(stale, p)
+	}
+
+	for p, tags := range collectTags(ctx, w.s.fsys, stale, w.s.jobs) {
+		w.cache.Notes[p] = cacheEntry{noteStat: stats[p], Tags: tags}
+		modified = true
+	}
+
+	return modified
+}
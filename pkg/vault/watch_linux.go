@@ -0,0 +1,178 @@
+package vault
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the inotify events that may change the notes of a
+// directory. Writes are reported once the file is closed.
+const inotifyMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// inotify watches every directory of a vault with inotify, which does not
+// watch directories recursively: directories created in the vault are
+// watched as they appear.
+type inotify struct {
+	root string
+	fd   int
+	file *os.File
+
+	// dirs maps watch descriptors to the vault-relative path of the directory
+	// they watch. It is only used by the reading goroutine once started.
+	dirs map[int]string
+
+	events chan fsEvent
+	errors chan error
+	done   chan struct{}
+}
+
+func newNotifier(root string) (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	n := &inotify{
+		root: root,
+		fd:   fd,
+		// a non-blocking file is read through the runtime poller, so that
+		// Close interrupts a pending read
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		dirs:   make(map[int]string),
+		events: make(chan fsEvent),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+
+	if err := n.addTree("."); err != nil {
+		n.file.Close()
+		return nil, err
+	}
+
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) Events() <-chan fsEvent { return n.events }
+
+func (n *inotify) Errors() <-chan error { return n.errors }
+
+func (n *inotify) Close() error {
+	select {
+	case <-n.done:
+		return nil
+	default:
+		close(n.done)
+	}
+	return n.file.Close()
+}
+
+// addTree watches the directory at the vault-relative path dir and all of its
+// subdirectories. The .git directory is skipped, except for .git/info, which
+// holds the exclude file of the repository.
+func (n *inotify) addTree(dir string) error {
+	return fs.WalkDir(os.DirFS(n.root), dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// entries may be removed while walking
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if d.Name() == ".git" {
+			if err := n.add(path.Join(name, "info")); err != nil && !errors.Is(err, unix.ENOENT) {
+				return err
+			}
+			return fs.SkipDir
+		}
+
+		return n.add(name)
+	})
+}
+
+// add watches the directory at the vault-relative path dir. n.file.Fd is not
+// used, since it would make reads blocking.
+func (n *inotify) add(dir string) error {
+	wd, err := unix.InotifyAddWatch(n.fd, filepath.Join(n.root, filepath.FromSlash(dir)), inotifyMask|unix.IN_ONLYDIR)
+	if err != nil {
+		return &fs.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+
+	n.dirs[wd] = dir
+	return nil
+}
+
+// read reads events until the notifier is closed, and sends them on the
+// events channel.
+func (n *inotify) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for {
+		m, err := n.file.Read(buf)
+		if err != nil {
+			select {
+			case n.errors <- err:
+			case <-n.done:
+			}
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= m; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			name := strings.TrimRight(string(buf[off+unix.SizeofInotifyEvent:off+unix.SizeofInotifyEvent+int(raw.Len)]), "\x00")
+			off += unix.SizeofInotifyEvent + int(raw.Len)
+
+			e, ok := n.event(raw, name)
+			if !ok {
+				continue
+			}
+
+			select {
+			case n.events <- e:
+			case <-n.done:
+				return
+			}
+		}
+	}
+}
+
+// event converts a raw inotify event about the entry name of a watched
+// directory. Directories created or moved into the vault are watched.
+func (n *inotify) event(raw *unix.InotifyEvent, name string) (fsEvent, bool) {
+	if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+		return fsEvent{Name: ".", Dir: true}, true
+	}
+
+	dir, ok := n.dirs[int(raw.Wd)]
+	if raw.Mask&unix.IN_IGNORED != 0 {
+		delete(n.dirs, int(raw.Wd))
+	}
+	if !ok || name == "" {
+		return fsEvent{}, false
+	}
+
+	e := fsEvent{Name: path.Join(dir, name), Dir: raw.Mask&unix.IN_ISDIR != 0}
+	if e.Dir && raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		if err := n.addTree(e.Name); err != nil {
+			// changes to the directory would be missed
+			select {
+			case n.errors <- err:
+			default:
+			}
+		}
+	}
+
+	return e, true
+}
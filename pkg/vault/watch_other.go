@@ -0,0 +1,133 @@
+//go:build !linux
+
+package vault
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// pollInterval is the interval between two listings of a polled vault.
+const pollInterval = time.Second
+
+// poller lists the files of a vault every pollInterval and reports those that
+// were added, removed, or whose size or modification time changed.
+type poller struct {
+	fsys  fs.FS
+	files map[string]noteStat
+
+	events chan fsEvent
+	errors chan error
+	done   chan struct{}
+}
+
+func newNotifier(root string) (notifier, error) {
+	p := &poller{
+		fsys:   os.DirFS(root),
+		events: make(chan fsEvent),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+
+	files, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	p.files = files
+
+	go p.poll()
+	return p, nil
+}
+
+func (p *poller) Events() <-chan fsEvent { return p.events }
+
+func (p *poller) Errors() <-chan error { return p.errors }
+
+func (p *poller) Close() error {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+	return nil
+}
+
+// list returns the stat of every file of the vault. The .git directory is
+// skipped, except for the exclude file of the repository.
+func (p *poller) list() (map[string]noteStat, error) {
+	files := make(map[string]noteStat)
+	if info, err := fs.Stat(p.fsys, infoExcludeFile); err == nil {
+		files[infoExcludeFile] = newNoteStat(info)
+	}
+
+	err := fs.WalkDir(p.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the root of the vault must exist, but entries may be removed
+			// while walking
+			if name == "." {
+				return err
+			}
+			return nil
+		}
+
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[name] = newNoteStat(info)
+		return nil
+	})
+
+	return files, err
+}
+
+func (p *poller) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		files, err := p.list()
+		if err != nil {
+			select {
+			case p.errors <- err:
+			case <-p.done:
+			}
+			return
+		}
+
+		var changed []string
+		for name, st := range files {
+			if old, ok := p.files[name]; !ok || old != st {
+				changed = append(changed, name)
+			}
+		}
+		for name := range p.files {
+			if _, ok := files[name]; !ok {
+				changed = append(changed, name)
+			}
+		}
+		p.files = files
+
+		for _, name := range changed {
+			select {
+			case p.events <- fsEvent{Name: name}:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
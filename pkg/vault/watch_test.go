@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func TestScanner_Watch(t *testing.T) {
+	dir := fs.NewDir(t, "test",
+		fs.WithFile("a.md", "#golang"),
+		fs.WithFile("image.png", ""),
+	)
+	defer dir.Remove()
+
+	write := func(rel, content string) func() error {
+		return func() error {
+			p := dir.Join(filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return err
+			}
+			return os.WriteFile(p, []byte(content), 0o644)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		change func() error
+		want   map[string]int
+	}{
+		{
+			name:   "note changed",
+			change: write("a.md", "#golang #cli"),
+			want:   map[string]int{"golang": 1, "cli": 1},
+		},
+		{
+			name:   "note added in new directory",
+			change: write("sub/deep/b.md", "#rust #cli"),
+			want:   map[string]int{"golang": 1, "cli": 2, "rust": 1},
+		},
+		{
+			name:   "note added in watched new directory",
+			change: write("sub/deep/c.md", "#rust"),
+			want:   map[string]int{"golang": 1, "cli": 2, "rust": 2},
+		},
+		{
+			name:   "exclude file changed",
+			change: write(ExcludeFile, "cli"),
+			want:   map[string]int{"golang": 1, "rust": 2},
+		},
+		{
+			name:   "ignore file changed",
+			change: write(".gitignore", "c.md"),
+			want:   map[string]int{"golang": 1, "rust": 1},
+		},
+		{
+			name: "directory moved out",
+			change: func() error {
+				return os.Rename(dir.Join("sub"), filepath.Join(t.TempDir(), "sub"))
+			},
+			want: map[string]int{"golang": 1},
+		},
+		{
+			name:   "note removed",
+			change: func() error { return os.Remove(dir.Join("a.md")) },
+			want:   map[string]int{},
+		},
+	}
+
+	r := require.New(t)
+
+	s, err := NewScanner(dir.Path(), WithExcludeFile())
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	results := make(chan *Result)
+	done := make(chan error)
+	go func() {
+		done <- s.Watch(ctx, 20*time.Millisecond, func(res *Result) error {
+			results <- res
+			return nil
+		})
+	}()
+
+	// next waits for a result with the wanted counts, skipping intermediate
+	// results of changes split across several updates
+	next := func(want map[string]int) *Result {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case res := <-results:
+				if maps.Equal(want, res.Counts) {
+					return res
+				}
+			case <-timeout:
+				r.FailNow("no result with counts", "%v", want)
+			}
+		}
+	}
+
+	next(map[string]int{"golang": 1})
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(_ *testing.T) {
+			r.NoError(tt.change())
+			next(tt.want)
+		})
+	}
+
+	// the cache is kept up to date
+	c, err := readCache(s.root, "")
+	r.NoError(err)
+	r.Empty(c.Notes)
+
+	cancel()
+	r.ErrorIs(<-done, context.Canceled)
+}
+
+func TestScanner_Watch_ErrorCases(t *testing.T) {
+	r := require.New(t)
+
+	s := NewFSScanner(fstest.MapFS{}, "vault.zip")
+	err := s.Watch(t.Context(), time.Millisecond, func(*Result) error { return nil })
+	r.ErrorIs(err, ErrReadOnly)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("a.md", "#golang"))
+	defer dir.Remove()
+
+	s, err = NewScanner(dir.Path())
+	r.NoError(err)
+
+	// errors returned by fn stop watching
+	err = s.Watch(t.Context(), time.Millisecond, func(*Result) error { return os.ErrClosed })
+	r.ErrorIs(err, os.ErrClosed)
+}
@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteNote replaces the content of the note at the slash-separated,
+// vault-relative path rel. The note is replaced atomically and keeps its
+// permissions.
+//
+// Returns ErrReadOnly if the vault is not a directory on disk.
+func (s *Scanner) WriteNote(rel, content string) error {
+	if s.root == "" {
+		return ErrReadOnly
+	}
+	return writeFileAtomic(s.root.join(rel), []byte(content), 0o644)
+}
+
+// writeFileAtomic writes data to the file at path. The data is written to a
+// temporary file in the same directory, which is then renamed over path, so
+// that the file is never left partially written, even if the process is
+// interrupted. An existing file keeps its permissions; a new file is created
+// with perm.
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) (err error) {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
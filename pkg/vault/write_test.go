@@ -0,0 +1,47 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/fs"
+)
+
+func Test_writeFileAtomic(t *testing.T) {
+	r := require.New(t)
+
+	dir := fs.NewDir(t, "test", fs.WithFile("a.md", "before", fs.WithMode(0o600)))
+	defer dir.Remove()
+
+	// an existing file keeps its permissions
+	path := filepath.Join(dir.Path(), "a.md")
+	r.NoError(writeFileAtomic(path, []byte("after"), 0o644))
+
+	got, err := os.ReadFile(path)
+	r.NoError(err)
+	r.Equal("after", string(got))
+
+	info, err := os.Stat(path)
+	r.NoError(err)
+	r.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// a new file is created with the given permissions
+	path = filepath.Join(dir.Path(), "b.md")
+	r.NoError(writeFileAtomic(path, []byte("new"), 0o640))
+
+	info, err = os.Stat(path)
+	r.NoError(err)
+	r.Equal(os.FileMode(0o640), info.Mode().Perm())
+
+	// no temporary file is left behind
+	entries, err := os.ReadDir(dir.Path())
+	r.NoError(err)
+	r.Len(entries, 2)
+
+	r.Error(writeFileAtomic(filepath.Join(dir.Path(), "missing", "c.md"), []byte("new"), 0o644))
+	entries, err = os.ReadDir(dir.Path())
+	r.NoError(err)
+	r.Len(entries, 2)
+}